@@ -1,10 +1,14 @@
 package socketio09
 
-import "sync"
+import (
+	"encoding/json"
+	"sync"
+)
 
 /*
 AckManager processes response listeners for socketio messages where ack is expected,
 and the user put a callback handler in place. A listener is a channel to the handler func.
+The zero value is ready to use.
 */
 type AckManager struct {
 	// counter is ack ID
@@ -12,16 +16,16 @@ type AckManager struct {
 	counterLock sync.Mutex
 
 	// int is the counter/ID
-	// string is the raw message data
-	responseListeners     map[int](chan string)
+	// json.RawMessage is the raw ack args
+	responseListeners     map[int](chan json.RawMessage)
 	responseListenersLock sync.RWMutex
 }
 
 /*
-getNextID provisions the next ACK id, in a thread safe way. counter starts at 0 by virtue of
+NextID provisions the next ACK id, in a thread safe way. counter starts at 0 by virtue of
 being an int, and gets iterated before being returned. So first ackID is 1.
 */
-func (a *AckManager) getNextID() int {
+func (a *AckManager) NextID() int {
 	a.counterLock.Lock()
 	defer a.counterLock.Unlock()
 
@@ -29,24 +33,29 @@ func (a *AckManager) getNextID() int {
 	return a.counter
 }
 
-func (a *AckManager) addListener(id int, w chan string) {
+// AddListener registers w to receive the args of the ack for message id
+func (a *AckManager) AddListener(id int, w chan json.RawMessage) {
 	a.responseListenersLock.Lock()
+	if a.responseListeners == nil {
+		a.responseListeners = make(map[int](chan json.RawMessage))
+	}
 	a.responseListeners[id] = w
 	a.responseListenersLock.Unlock()
 }
 
-func (a *AckManager) removeListener(id int) {
+// RemoveListener forgets the listener for message id, if there is one
+func (a *AckManager) RemoveListener(id int) {
 	a.responseListenersLock.Lock()
 	delete(a.responseListeners, id)
 	a.responseListenersLock.Unlock()
 }
 
 /*
-getListener returns an ack listener and removes it.
+Listener returns an ack listener and removes it.
 */
-func (a *AckManager) getListener(id int) (chan string, error) {
-	a.responseListenersLock.RLock()
-	defer a.responseListenersLock.RUnlock()
+func (a *AckManager) Listener(id int) (chan json.RawMessage, error) {
+	a.responseListenersLock.Lock()
+	defer a.responseListenersLock.Unlock()
 
 	listener, exists := a.responseListeners[id]
 	if exists {
@@ -55,3 +64,10 @@ func (a *AckManager) getListener(id int) (chan string, error) {
 	}
 	return nil, ErrorAckListenerNotFound
 }
+
+// Pending counts the acks still awaited
+func (a *AckManager) Pending() int {
+	a.responseListenersLock.RLock()
+	defer a.responseListenersLock.RUnlock()
+	return len(a.responseListeners)
+}
@@ -0,0 +1,119 @@
+package socketio09
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Clock tells the time to a client: heartbeats, ack timeouts, the write batch window and the
+handshake all go through it. Tests set a FakeClock on the transport to drive timeouts without
+waiting for them. Web socket read and write deadlines are enforced by the operating system, so
+they always use the real time.
+
+The client does not reconnect by itself, so there is no reconnect backoff for the Clock to
+drive; code which reconnects with Connect can time its backoff with the same Clock.
+*/
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock used when the transport has none
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+/*
+FakeClock is a Clock which only moves when Advance is called.
+
+	clock := socketio09.NewFakeClock(time.Now())
+	transport.Clock = clock
+	client := socketio09.NewClient(conn, transport)
+	clock.BlockUntil(1) // the heartbeat service is sleeping
+	clock.Advance(transport.HeartbeatInterval)
+*/
+type FakeClock struct {
+	now     time.Time
+	waiters []fakeWaiter
+	lock    sync.Mutex
+	changed *sync.Cond
+}
+
+type fakeWaiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewFakeClock returns a FakeClock reading now
+func NewFakeClock(now time.Time) *FakeClock {
+	fc := &FakeClock{now: now}
+	fc.changed = sync.NewCond(&fc.lock)
+	return fc
+}
+
+// Now returns the fake time
+func (fc *FakeClock) Now() time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	return fc.now
+}
+
+// Sleep blocks until the clock has been advanced by d
+func (fc *FakeClock) Sleep(d time.Duration) {
+	<-fc.After(d)
+}
+
+// After sends the fake time once the clock has been advanced by d
+func (fc *FakeClock) After(d time.Duration) <-chan time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- fc.now
+		return ch
+	}
+	fc.waiters = append(fc.waiters, fakeWaiter{until: fc.now.Add(d), ch: ch})
+	fc.changed.Broadcast()
+	return ch
+}
+
+/*
+Advance moves the clock forward by d, waking every Sleep and After which is due, earliest first.
+*/
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fc.now = fc.now.Add(d)
+	sort.SliceStable(fc.waiters, func(i, j int) bool { return fc.waiters[i].until.Before(fc.waiters[j].until) })
+	waiting := fc.waiters[:0]
+	for _, w := range fc.waiters {
+		if w.until.After(fc.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.ch <- fc.now
+	}
+	fc.waiters = waiting
+	fc.changed.Broadcast()
+}
+
+/*
+BlockUntil waits until n Sleep or After calls are waiting on the clock, so a test knows the
+goroutines it wants to wake have got there before it calls Advance.
+*/
+func (fc *FakeClock) BlockUntil(n int) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+	for len(fc.waiters) < n {
+		fc.changed.Wait()
+	}
+}
@@ -0,0 +1,53 @@
+package socketio09
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockHeartbeat(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.HeartbeatInterval = 10 * time.Second
+	transport.Clock = clock
+	client := NewClient(clientEnd, transport)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(10 * time.Second)
+		if frame, _ := server.GetNextMsg(); frame != "2::" {
+			t.Fatalf("got %q, want a heartbeat", frame)
+		}
+	}
+}
+
+func TestFakeClockAckTimeout(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.ReceiveTimeout = time.Minute
+	transport.Clock = clock
+	client := NewClient(clientEnd, transport)
+	defer client.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := client.EmitWithAck("ping", nil)
+		errs <- err
+	}()
+	server.GetNextMsg()
+	clock.BlockUntil(1)
+
+	clock.Advance(59 * time.Second)
+	select {
+	case err := <-errs:
+		t.Fatalf("timed out early: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if err := <-errs; err != ErrorSendTimeout {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+}
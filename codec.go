@@ -0,0 +1,90 @@
+package socketio09
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+/*
+Codec marshals and unmarshals the JSON carried by socket.io packets: event envelopes, emitted
+args and the args given to handlers. Set one on WebsocketTransport.Codec to replace
+encoding/json.
+
+Implementations must handle json.RawMessage like encoding/json does, writing it out verbatim
+when marshalling and capturing the raw bytes when unmarshalling, because args are passed around
+undecoded until a handler needs them.
+*/
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+/*
+JSONCodec is the default Codec, built on encoding/json. Its zero value behaves exactly like
+json.Marshal and json.Unmarshal. DisallowUnknownFields and UseNumber only apply to the args given
+to handlers: event envelopes are decoded without them, so a peer adding a field to an envelope is
+not refused.
+*/
+type JSONCodec struct {
+	// DisallowUnknownFields fails decoding when an object has a key which does not match any
+	// field of the destination struct.
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into an interface{} as json.Number instead of float64.
+	UseNumber bool
+	// DisableHTMLEscaping stops <, > and & from being escaped in outgoing strings.
+	DisableHTMLEscaping bool
+}
+
+// DefaultCodec is used when the transport has no Codec set
+var DefaultCodec Codec = &JSONCodec{}
+
+// Marshal encodes v to JSON
+func (jc *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	if !jc.DisableHTMLEscaping {
+		return json.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encoder terminates each value with a newline, which Marshal does not
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+/*
+envelopeCodec is the codec decoding event envelopes, which is codec without the decoding options
+of a JSONCodec
+*/
+func envelopeCodec(codec Codec) Codec {
+	if jc, ok := codec.(*JSONCodec); ok && (jc.DisallowUnknownFields || jc.UseNumber) {
+		return &JSONCodec{}
+	}
+	return codec
+}
+
+// Unmarshal decodes JSON data into v
+func (jc *JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if !jc.DisallowUnknownFields && !jc.UseNumber {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if jc.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if jc.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// json.Unmarshal refuses anything after the value, so the decoder should too
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrorCodecTrailingData
+	}
+	return nil
+}
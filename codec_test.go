@@ -0,0 +1,67 @@
+package socketio09
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONCodecZeroValueMatchesEncodingJSON(t *testing.T) {
+	v := map[string]string{"html": "<b>&</b>"}
+	want, _ := json.Marshal(v)
+	got, err := (&JSONCodec{}).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestJSONCodecDisableHTMLEscaping(t *testing.T) {
+	got, err := (&JSONCodec{DisableHTMLEscaping: true}).Marshal([]interface{}{"<b>&</b>", json.RawMessage(`{"a":"<"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `["<b>&</b>",{"a":"<"}]` {
+		t.Fatalf("Marshal() = %s", got)
+	}
+}
+
+func TestJSONCodecStrictDecoding(t *testing.T) {
+	type args struct {
+		Name string `json:"name"`
+	}
+	codec := &JSONCodec{DisallowUnknownFields: true}
+	if err := codec.Unmarshal([]byte(`{"name":"a","extra":1}`), &args{}); err == nil {
+		t.Fatal("expected unknown field to be rejected")
+	}
+	if err := codec.Unmarshal([]byte(`{"name":"a"} {}`), &args{}); err != ErrorCodecTrailingData {
+		t.Fatalf("trailing data err = %v", err)
+	}
+
+	var n interface{}
+	if err := (&JSONCodec{UseNumber: true}).Unmarshal([]byte(`12345678901234567890`), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n != json.Number("12345678901234567890") {
+		t.Fatalf("UseNumber gave %#v", n)
+	}
+}
+
+func TestJSONCodecStrictArgsOnly(t *testing.T) {
+	codec := &JSONCodec{DisallowUnknownFields: true}
+	msg, err := DecodeInboundMessageWithCodec(`5:::{"name":"say","args":[{"name":"a"}],"extra":1}`, codec)
+	if err != nil {
+		t.Fatalf("envelope with an extra field refused: %v", err)
+	}
+
+	var args []struct {
+		Name string `json:"name"`
+	}
+	if err := codec.Unmarshal(msg.Args, &args); err != nil || args[0].Name != "a" {
+		t.Fatalf("args decoded as %v, %v", args, err)
+	}
+	if err := codec.Unmarshal([]byte(`[{"name":"a","extra":1}]`), &args); err == nil {
+		t.Fatal("expected unknown field in the args to be rejected")
+	}
+}
@@ -0,0 +1,27 @@
+package socketio09
+
+/*
+Conn is a transport connection carrying socket.io frames, one frame per message.
+WebsocketConnection is the one Connect uses; PipeConn connects a client to a test in memory.
+Wrapping a Conn is the way to observe or disturb the frames of a client.
+*/
+type Conn interface {
+	// GetNextMsg blocks until the next frame arrives
+	GetNextMsg() (string, error)
+	// WriteMsg writes one frame
+	WriteMsg(message string) error
+	// WriteMsgs writes several frames in one go, for write batching. Network transports which
+	// carry one frame per message join them with EncodePayload; PipeConn, which has no wire to
+	// save messages on, passes them to the other end one by one.
+	WriteMsgs(messages []string) error
+	// Close closes the connection, making pending and later calls fail
+	Close()
+}
+
+var (
+	_ Conn = (*WebsocketConnection)(nil)
+	_ Conn = (*PipeConn)(nil)
+	_ Conn = (*FaultyConn)(nil)
+	_ Conn = (*RecordingConn)(nil)
+	_ Conn = (*ReplayConn)(nil)
+)
@@ -1,15 +1,97 @@
 package socketio09
 
-import "testing"
+import (
+	"testing"
 
-func TestInboundMessageTypeFromDataIsProperlyParsed(t *testing.T) {
+	"github.com/ruffrey/go-socketio09/spec"
+)
 
+func TestInboundMessageTypeFromDataIsProperlyParsed(t *testing.T) {
+	for frame, want := range map[string]string{
+		"0::":                        spec.Disconnect,
+		"1::":                        spec.Connect,
+		"2::":                        spec.Heartbeat,
+		"3:::hello":                  spec.TextMessage,
+		"4:::{}":                     spec.JSONMessage,
+		`5:::{"name":"a","args":[]}`: spec.Event,
+		"6:::1":                      spec.Ack,
+		"7:::reason":                 spec.Error,
+		"8::":                        spec.Noop,
+	} {
+		m, err := DecodeInboundMessage(frame)
+		if err != nil {
+			t.Errorf("DecodeInboundMessage(%q) failed: %v", frame, err)
+			continue
+		}
+		if m.Type != want {
+			t.Errorf("DecodeInboundMessage(%q).Type = %q, want %q", frame, m.Type, want)
+		}
+	}
 }
 
 func TestGetAckIDFromStringMessageWorks(t *testing.T) {
+	m, err := DecodeInboundMessage(`6:::12+["A","B"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.AckID != 12 || string(m.Args) != `["A","B"]` {
+		t.Fatalf("got ack %d args %q", m.AckID, m.Args)
+	}
 
+	m, err = DecodeInboundMessage("6:::4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.AckID != 4 || len(m.Args) != 0 {
+		t.Fatalf("got ack %d args %q", m.AckID, m.Args)
+	}
 }
 
 func TestParsinEventNameFromMessage(t *testing.T) {
+	m, err := DecodeInboundMessage(`5:7+:/chat:{"name":"news","args":[{"a":1}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.EventName != "news" || m.Endpoint != "/chat" || m.AckID != 7 {
+		t.Fatalf("got name %q endpoint %q id %d", m.EventName, m.Endpoint, m.AckID)
+	}
+	if string(m.Args) != `[{"a":1}]` {
+		t.Fatalf("got args %q", m.Args)
+	}
+}
+
+type benchmarkTick struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+	Volume int     `json:"volume"`
+}
+
+const benchmarkEventFrame = `5:::{"name":"tick","args":[{"symbol":"ACME","price":101.25,"volume":300},{"symbol":"INIT","price":7.5,"volume":12000}]}`
+
+func BenchmarkDecodeInboundMessage(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeInboundMessage(benchmarkEventFrame); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeAndDispatchEvent(b *testing.B) {
+	m := &eventEmitter{}
+	m.initMethods()
+	err := m.On("tick", func(c *SocketIOConnection, ticks []benchmarkTick) {})
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := &SocketIOConnection{}
 
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg, err := DecodeInboundMessage(benchmarkEventFrame)
+		if err != nil {
+			b.Fatal(err)
+		}
+		m.checkAndFireListenersForValidMessage(c, msg)
+	}
 }
@@ -1,6 +1,9 @@
 package socketio09
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"strconv"
 
 	"github.com/ruffrey/go-socketio09/spec"
@@ -10,23 +13,103 @@ import (
 EncodeOutboundMessage is used to make an outgoing message. A *Message is transformed into a socket.io frame
 message string, to be sent over a web socket connection.
 
-At this point in time, we are only going to be sending hearbeats (2) and events (5).
+Events with an AckID ask the other side for an ack, and acks carry the AckID being acknowledged.
+Text (3), JSON (4) and error (7) messages send Data as is.
 */
 func EncodeOutboundMessage(m *Message) (msg string, err error) {
+	return EncodeOutboundMessageWithCodec(m, DefaultCodec)
+}
+
+// EncodeOutboundMessageWithCodec is EncodeOutboundMessage, encoding event JSON with codec
+func EncodeOutboundMessageWithCodec(m *Message, codec Codec) (msg string, err error) {
+	p := &Packet{
+		Type:     m.Type,
+		Endpoint: m.Endpoint,
+	}
+
 	switch m.Type {
-	case spec.Heartbeat:
-		msg = spec.Heartbeat
-		return msg, nil
+	case spec.Disconnect, spec.Connect, spec.Heartbeat, spec.Noop:
 	case spec.Event:
-		msg = spec.Event
-		if m.AckID != 0 {
-			msg += ":" + strconv.Itoa(m.AckID) + `+::{"name":"` + m.EventName + `","args":[` + m.Args + `]}`
-		} else {
-			msg += `:::{"name":"` + m.EventName + `","args":[` + m.Args + `]}`
+		p.ID = m.AckID
+		p.AckRequested = m.AckID != 0
+		p.Data, err = encodeEventData(m.EventName, m.Args, codec)
+		if err != nil {
+			return msg, err
+		}
+	case spec.Ack:
+		p.Data = strconv.Itoa(m.AckID)
+		if len(m.Args) != 0 {
+			p.Data += "+" + string(m.Args)
+		}
+	case spec.TextMessage, spec.JSONMessage, spec.Error:
+		p.ID = m.AckID
+		p.Data = m.Data
+	default:
+		// this should not happen
+		return msg, ErrorProtocolUnexpectedOutboundMessageType
+	}
+
+	return p.Encode()
+}
+
+/*
+EncodeFrame encodes a message for sending, with args, unless nil, as its single argument. The
+trace context of ctx is added to the args of an event when propagator is set. Clients and the
+server package both encode what they emit with it.
+*/
+func EncodeFrame(ctx context.Context, msg *Message, args interface{}, codec Codec, propagator Propagator) (string, error) {
+	if args != nil {
+		// args is the single argument of the event, and the protocol wants an array of them
+		json, err := codec.Marshal([]interface{}{args})
+		if err != nil {
+			return "", err
+		}
+		msg.Args = json
+	}
+	if msg.Type == spec.Event {
+		json, err := InjectTrace(propagator, ctx, msg.Args)
+		if err != nil {
+			return "", err
 		}
-		return msg, nil
+		msg.Args = json
 	}
+	return EncodeOutboundMessageWithCodec(msg, codec)
+}
+
+/*
+encodeEventData produces the `{"name":...,"args":[...]}` body of an event. args must already be
+a JSON array, or empty for no args.
+*/
+func encodeEventData(name string, args json.RawMessage, codec Codec) (string, error) {
+	if err := validateEventName(name); err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		args = json.RawMessage("[]")
+	}
+
+	data, err := codec.Marshal(socketioEventMessage{
+		Name: name,
+		Args: args,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
 
-	// this should not happen
-	return msg, ErrorProtocolUnexpectedOutboundMessageType
+/*
+validateEventName checks that name may be emitted, since the spec reserves some names for
+socket.io itself.
+*/
+func validateEventName(name string) error {
+	if name == "" {
+		return ErrorProtocolInvalidEventName
+	}
+	for _, reserved := range spec.ReservedEventNames {
+		if name == reserved {
+			return fmt.Errorf("%w: %q", ErrorProtocolReservedEventName, name)
+		}
+	}
+	return nil
 }
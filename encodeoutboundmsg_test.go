@@ -0,0 +1,76 @@
+package socketio09
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+func TestEncodeEventEscapesName(t *testing.T) {
+	frame, err := EncodeOutboundMessage(&Message{
+		Type:      spec.Event,
+		EventName: `a","args":["injected"],"x":"`,
+		Args:      json.RawMessage(`[1]`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := DecodeInboundMessage(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.EventName != `a","args":["injected"],"x":"` || string(m.Args) != "[1]" {
+		t.Fatalf("event did not survive encoding: %q", frame)
+	}
+}
+
+func TestEncodeEventWithAckAndNoArgs(t *testing.T) {
+	frame, err := EncodeOutboundMessage(&Message{Type: spec.Event, EventName: "ping", AckID: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame != `5:3+::{"name":"ping","args":[]}` {
+		t.Fatalf("got %q", frame)
+	}
+}
+
+func TestEncodeFrame(t *testing.T) {
+	ctx := context.WithValue(context.Background(), traceKey{}, "t1")
+	frame, err := EncodeFrame(ctx, &Message{Type: spec.Event, EventName: "say"}, "hi", DefaultCodec, testPropagator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame != `5:::{"name":"say","args":["hi",{"$trace":{"traceparent":"t1"}}]}` {
+		t.Fatalf("event encoded as %q", frame)
+	}
+
+	frame, err = EncodeFrame(ctx, &Message{Type: spec.Ack, AckID: 2}, "ok", DefaultCodec, testPropagator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame != `6:::2+["ok"]` {
+		t.Fatalf("ack encoded as %q", frame)
+	}
+}
+
+func TestEncodeEventRejectsInvalidNames(t *testing.T) {
+	_, err := EncodeOutboundMessage(&Message{Type: spec.Event, EventName: ""})
+	if err != ErrorProtocolInvalidEventName {
+		t.Errorf("empty name err = %v", err)
+	}
+	for _, name := range spec.ReservedEventNames {
+		_, err := EncodeOutboundMessage(&Message{Type: spec.Event, EventName: name})
+		if !errors.Is(err, ErrorProtocolReservedEventName) {
+			t.Errorf("reserved name %q err = %v", name, err)
+		}
+	}
+}
+
+func TestEncodeEventRejectsInvalidArgs(t *testing.T) {
+	if _, err := EncodeOutboundMessage(&Message{Type: spec.Event, EventName: "a", Args: json.RawMessage("[1")}); err == nil {
+		t.Fatal("expected an error for broken args JSON")
+	}
+}
@@ -18,6 +18,10 @@ var (
 	ErrorSendTimeout = errors.New("Timeout")
 	// ErrorSocketOverflood is an error
 	ErrorSocketOverflood = errors.New("Socket is flooded")
+	// ErrorReplayMismatch indicates a client did not write the frames of the recording it replays
+	ErrorReplayMismatch = errors.New("Replayed client diverged from the recording")
+	// ErrorCodecTrailingData indicates there was more data after the JSON value being decoded
+	ErrorCodecTrailingData = errors.New("invalid JSON: unexpected data after top-level value")
 
 	/* Protocol Errors */
 
@@ -27,6 +31,12 @@ var (
 	ErrorProtocolUnexpectedOutboundMessageType = errors.New("Protocol Error: unexpected outbound message type")
 	// ErrorProtocolReceivedInvalidPacket is an error
 	ErrorProtocolReceivedInvalidPacket = errors.New("Protocol Error: invalid packet type received")
+	// ErrorProtocolInvalidEventName indicates an event name was empty
+	ErrorProtocolInvalidEventName = errors.New("Protocol Error: event name must not be empty")
+	// ErrorProtocolReservedEventName indicates an event name is reserved by the spec and cannot be emitted
+	ErrorProtocolReservedEventName = errors.New("Protocol Error: event name is reserved")
+	// ErrorProtocolInvalidPayload indicates a multi-message payload had broken framing
+	ErrorProtocolInvalidPayload = errors.New("Protocol Error: invalid multi-message payload framing")
 
 	/* Web Socket Errors */
 
@@ -34,8 +44,15 @@ var (
 	ErrorTransportOnlySupportsText = errors.New("Received non-TextMessage at websocket")
 	// ErrorTransportBufferError is an error
 	ErrorTransportBufferError = errors.New("Buffer error (buffer may not be a buffer)")
+	// ErrorTransportClosed indicates the connection was closed
+	ErrorTransportClosed = errors.New("Connection is closed")
 	// ErrorTransportEmptyPacket is an error
 	ErrorTransportEmptyPacket = errors.New("Web socket message is empty and that is not allowed")
 	// ErrorHTTPUpgradeFailed is an error
 	ErrorHTTPUpgradeFailed = errors.New("Failure during HTTP upgrade attempt")
+	// ErrorHandshakeFailed indicates the server refused the handshake, or answered it with something
+	// other than a handshake
+	ErrorHandshakeFailed = errors.New("Handshake failed")
+	// ErrorForceDisconnectFailed indicates the server did not answer a forced disconnect with 200 OK
+	ErrorForceDisconnectFailed = errors.New("Forced disconnect was not accepted by the server")
 )
@@ -1,8 +1,7 @@
 package socketio09
 
 import (
-	"encoding/json"
-	"log"
+	"context"
 	"sync"
 
 	"github.com/ruffrey/go-socketio09/spec"
@@ -78,32 +77,39 @@ func (m *eventEmitter) checkAndFireListenersForValidMessage(c *SocketIOConnectio
 		m.fireEvent(c, OnConnect)
 		return
 	case spec.Disconnect:
-		CloseChannel(c, m)
+		CloseChannel(c, m, ReasonServerDisconnect)
 		return
 	case spec.Event:
 		fn, exists := m.findHandlerForEvent(msg.EventName)
 		if !exists {
 			return
 		}
+		ctx, args := ExtractTrace(c.transport.propagator(), context.Background(), msg.Args)
+		_, span := c.transport.tracer().Start(ctx, SpanHandler, AttrEvent, msg.EventName,
+			AttrAckID, msg.AckID, AttrEndpoint, msg.Endpoint, AttrPayloadSize, len(msg.Args))
 		if !fn.ArgsPresent {
 			fn.callFunc(c, &struct{}{})
+			span.End(nil)
 			return
 		}
 
+		// decode straight into the handler's own argument type
 		data := fn.getArgs()
-		err := json.Unmarshal([]byte(msg.Args), &data)
+		err := c.codec().Unmarshal(args, data)
 
 		if err != nil {
-			log.Println(err, "likely msg was not valid json")
+			c.log().Warn("event args do not fit the handler", "event", msg.EventName, "err", err)
+			span.End(err)
 			return
 		}
 
 		fn.callFunc(c, data)
+		span.End(nil)
 		return
 	case spec.Ack:
-		listener, err := c.acks.getListener(msg.AckID)
+		listener, err := c.acks.Listener(msg.AckID)
 		if err != nil {
-			log.Println(err, "likely msg was not valid json")
+			c.log().Debug("ack nobody is waiting for, it may have timed out", "ack", msg.AckID)
 			return
 		}
 		listener <- msg.Args
@@ -0,0 +1,134 @@
+/*
+Package expvarmetrics publishes the metrics of socketio09 clients with expvar, under
+/debug/vars.
+
+	transport := socketio09.NewConnection()
+	transport.Metrics = expvarmetrics.New("socketio")
+*/
+package expvarmetrics
+
+import (
+	"expvar"
+	"time"
+)
+
+/*
+Metrics is a socketio09.Metrics adding up the activity of every client it is given to. The
+published map holds:
+
+	frames_in, frames_out          frames by packet type
+	bytes_in, bytes_out            frame bytes
+	acks, ack_rtt_ns               acks received, and their total round trip time
+	ack_timeouts                   EmitWithAck calls which timed out
+	queue_depth                    the last outbound queue depth reported
+	handshakes, handshake_errors   handshakes made, and those which failed
+	handshake_ns                   total handshake time
+	reconnects                     reconnect attempts
+	disconnects                    disconnects by reason
+*/
+type Metrics struct {
+	framesIn, framesOut *expvar.Map
+	bytesIn, bytesOut   *expvar.Int
+	acks, ackRTT        *expvar.Int
+	ackTimeouts         *expvar.Int
+	queueDepth          *expvar.Int
+	handshakes          *expvar.Int
+	handshakeErrors     *expvar.Int
+	handshakeTime       *expvar.Int
+	reconnects          *expvar.Int
+	disconnects         *expvar.Map
+
+	vars *expvar.Map
+}
+
+/*
+New publishes a map of metrics as name. Like expvar.Publish, it panics if name is taken, so call
+it once and share the result between clients.
+*/
+func New(name string) *Metrics {
+	m := newMetrics()
+	expvar.Publish(name, m.vars)
+	return m
+}
+
+func newMetrics() *Metrics {
+	m := &Metrics{
+		framesIn:        new(expvar.Map).Init(),
+		framesOut:       new(expvar.Map).Init(),
+		bytesIn:         new(expvar.Int),
+		bytesOut:        new(expvar.Int),
+		acks:            new(expvar.Int),
+		ackRTT:          new(expvar.Int),
+		ackTimeouts:     new(expvar.Int),
+		queueDepth:      new(expvar.Int),
+		handshakes:      new(expvar.Int),
+		handshakeErrors: new(expvar.Int),
+		handshakeTime:   new(expvar.Int),
+		reconnects:      new(expvar.Int),
+		disconnects:     new(expvar.Map).Init(),
+	}
+
+	vars := new(expvar.Map).Init()
+	vars.Set("frames_in", m.framesIn)
+	vars.Set("frames_out", m.framesOut)
+	vars.Set("bytes_in", m.bytesIn)
+	vars.Set("bytes_out", m.bytesOut)
+	vars.Set("acks", m.acks)
+	vars.Set("ack_rtt_ns", m.ackRTT)
+	vars.Set("ack_timeouts", m.ackTimeouts)
+	vars.Set("queue_depth", m.queueDepth)
+	vars.Set("handshakes", m.handshakes)
+	vars.Set("handshake_errors", m.handshakeErrors)
+	vars.Set("handshake_ns", m.handshakeTime)
+	vars.Set("reconnects", m.reconnects)
+	vars.Set("disconnects", m.disconnects)
+	m.vars = vars
+	return m
+}
+
+// FrameIn counts a frame read
+func (m *Metrics) FrameIn(packetType string, bytes int) {
+	m.framesIn.Add(packetType, 1)
+	m.bytesIn.Add(int64(bytes))
+}
+
+// FrameOut counts a frame written
+func (m *Metrics) FrameOut(packetType string, bytes int) {
+	m.framesOut.Add(packetType, 1)
+	m.bytesOut.Add(int64(bytes))
+}
+
+// AckRoundTrip counts an ack and its round trip time
+func (m *Metrics) AckRoundTrip(d time.Duration) {
+	m.acks.Add(1)
+	m.ackRTT.Add(int64(d))
+}
+
+// AckTimeout counts an ack which never came
+func (m *Metrics) AckTimeout() {
+	m.ackTimeouts.Add(1)
+}
+
+// QueueDepth keeps the last queue depth
+func (m *Metrics) QueueDepth(depth int) {
+	m.queueDepth.Set(int64(depth))
+}
+
+// Handshake counts a handshake and its duration
+func (m *Metrics) Handshake(d time.Duration, err error) {
+	m.handshakes.Add(1)
+	m.handshakeTime.Add(int64(d))
+	if err != nil {
+		m.handshakeErrors.Add(1)
+	}
+}
+
+// Reconnect counts a reconnect attempt
+func (m *Metrics) Reconnect(attempt int) {
+	m.reconnects.Add(1)
+}
+
+// Disconnect counts a disconnect by reason
+func (m *Metrics) Disconnect(reason string) {
+	m.disconnects.Add(reason, 1)
+}
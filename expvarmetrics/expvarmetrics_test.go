@@ -0,0 +1,43 @@
+package expvarmetrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+)
+
+var _ socketio09.Metrics = (*Metrics)(nil)
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+	clientEnd, server := socketio09.NewPipe()
+	transport := socketio09.NewConnection()
+	transport.ReceiveTimeout = time.Second
+	transport.Metrics = m
+	client := socketio09.NewClient(clientEnd, transport)
+
+	go func() {
+		server.GetNextMsg()
+		server.WriteMsg(`6:::1+["pong"]`)
+	}()
+	if _, err := client.EmitWithAck("ping", nil); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	var vars struct {
+		FramesIn    map[string]int `json:"frames_in"`
+		FramesOut   map[string]int `json:"frames_out"`
+		Acks        int            `json:"acks"`
+		Disconnects map[string]int `json:"disconnects"`
+	}
+	if err := json.Unmarshal([]byte(m.vars.String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.FramesIn["6"] != 1 || vars.FramesOut["5"] != 1 || vars.Acks != 1 ||
+		vars.Disconnects[socketio09.ReasonClientDisconnect] != 1 {
+		t.Fatalf("unexpected metrics %+v", vars)
+	}
+}
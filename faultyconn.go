@@ -0,0 +1,242 @@
+package socketio09
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Direction tells inbound frames, read with GetNextMsg, from outbound ones
+type Direction int
+
+const (
+	// Inbound frames are read from the connection
+	Inbound Direction = iota
+	// Outbound frames are written to the connection
+	Outbound
+)
+
+// String is "in" or "out"
+func (d Direction) String() string {
+	if d == Outbound {
+		return "out"
+	}
+	return "in"
+}
+
+// Fault is what happens to a frame passing through a FaultyConn
+type Fault int
+
+const (
+	// FaultNone lets the frame through
+	FaultNone Fault = iota
+	// FaultDrop loses the frame
+	FaultDrop
+	// FaultDuplicate delivers the frame twice
+	FaultDuplicate
+	// FaultTruncate delivers the first half of the frame
+	FaultTruncate
+	// FaultClose closes the connection instead of delivering the frame
+	FaultClose
+)
+
+/*
+Faults configures a FaultyConn. Rates are probabilities between 0 and 1, checked in the order
+close, drop, duplicate, truncate for each frame in either direction.
+*/
+type Faults struct {
+	// Latency delays every frame
+	Latency time.Duration
+	// Jitter adds up to this much random delay to every frame
+	Jitter time.Duration
+
+	DropRate      float64
+	DuplicateRate float64
+	TruncateRate  float64
+	CloseRate     float64
+
+	// Script, when set, picks the fault of each frame instead of the rates. n counts the frames
+	// of each direction from 1.
+	Script func(dir Direction, n int, frame string) Fault
+
+	// Rand is the source of randomness, so runs can be reproduced with a fixed seed
+	Rand *rand.Rand
+}
+
+/*
+FaultyConn wraps a Conn and disturbs the frames passing through it, to test how a client copes
+with a bad network.
+
+	faulty := socketio09.NewFaultyConn(conn, socketio09.Faults{
+		Latency:  50 * time.Millisecond,
+		DropRate: 0.1,
+		Rand:     rand.New(rand.NewSource(1)),
+	})
+	client := socketio09.NewClient(faulty, transport)
+*/
+type FaultyConn struct {
+	conn   Conn
+	faults Faults
+
+	count     [2]int
+	pending   []string // inbound duplicates still to be read
+	lock      sync.Mutex
+	readLock  sync.Mutex
+	writeLock sync.Mutex
+}
+
+/*
+NewFaultyConn wraps conn. A nil faults.Rand is seeded from the clock.
+*/
+func NewFaultyConn(conn Conn, faults Faults) *FaultyConn {
+	if faults.Rand == nil {
+		faults.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return &FaultyConn{conn: conn, faults: faults}
+}
+
+/*
+decide picks the fault for a frame, and how long to hold it
+*/
+func (fc *FaultyConn) decide(dir Direction, frame string) (Fault, time.Duration) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fc.count[dir]++
+	delay := fc.faults.Latency
+	if fc.faults.Jitter > 0 {
+		delay += time.Duration(fc.faults.Rand.Int63n(int64(fc.faults.Jitter)))
+	}
+	if fc.faults.Script != nil {
+		return fc.faults.Script(dir, fc.count[dir], frame), delay
+	}
+
+	switch {
+	case fc.roll(fc.faults.CloseRate):
+		return FaultClose, delay
+	case fc.roll(fc.faults.DropRate):
+		return FaultDrop, delay
+	case fc.roll(fc.faults.DuplicateRate):
+		return FaultDuplicate, delay
+	case fc.roll(fc.faults.TruncateRate):
+		return FaultTruncate, delay
+	}
+	return FaultNone, delay
+}
+
+// roll must be called with the lock held
+func (fc *FaultyConn) roll(rate float64) bool {
+	return rate > 0 && fc.faults.Rand.Float64() < rate
+}
+
+// GetNextMsg reads the next frame which is not dropped
+func (fc *FaultyConn) GetNextMsg() (string, error) {
+	fc.readLock.Lock()
+	defer fc.readLock.Unlock()
+
+	if len(fc.pending) > 0 {
+		frame := fc.pending[0]
+		fc.pending = fc.pending[1:]
+		return frame, nil
+	}
+
+	for {
+		frame, err := fc.conn.GetNextMsg()
+		if err != nil {
+			return "", err
+		}
+		fault, delay := fc.decide(Inbound, frame)
+		time.Sleep(delay)
+
+		switch fault {
+		case FaultDrop:
+			continue
+		case FaultDuplicate:
+			fc.pending = append(fc.pending, frame)
+		case FaultTruncate:
+			frame = truncate(frame)
+		case FaultClose:
+			fc.conn.Close()
+			return "", ErrorTransportClosed
+		}
+		return frame, nil
+	}
+}
+
+// WriteMsg writes a frame, unless it is dropped
+func (fc *FaultyConn) WriteMsg(message string) error {
+	fc.writeLock.Lock()
+	defer fc.writeLock.Unlock()
+
+	frames, closing := fc.disturb(message)
+	if closing {
+		fc.conn.Close()
+		return ErrorTransportClosed
+	}
+	for _, frame := range frames {
+		if err := fc.conn.WriteMsg(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+WriteMsgs disturbs the frames one by one, and writes those left in a single call to the wrapped
+connection, so a batching connection still puts them in one message.
+*/
+func (fc *FaultyConn) WriteMsgs(messages []string) error {
+	fc.writeLock.Lock()
+	defer fc.writeLock.Unlock()
+
+	var frames []string
+	for _, message := range messages {
+		disturbed, closing := fc.disturb(message)
+		if closing {
+			if len(frames) != 0 {
+				fc.conn.WriteMsgs(frames)
+			}
+			fc.conn.Close()
+			return ErrorTransportClosed
+		}
+		frames = append(frames, disturbed...)
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+	return fc.conn.WriteMsgs(frames)
+}
+
+/*
+disturb decides the fault of an outbound frame and waits out its delay. It returns the frames to
+write in its place, or closing when the connection is to be closed instead.
+*/
+func (fc *FaultyConn) disturb(message string) (frames []string, closing bool) {
+	fault, delay := fc.decide(Outbound, message)
+	time.Sleep(delay)
+
+	switch fault {
+	case FaultDrop:
+		return nil, false
+	case FaultDuplicate:
+		return []string{message, message}, false
+	case FaultTruncate:
+		return []string{truncate(message)}, false
+	case FaultClose:
+		return nil, true
+	}
+	return []string{message}, false
+}
+
+// Close closes the wrapped connection
+func (fc *FaultyConn) Close() {
+	fc.conn.Close()
+}
+
+// truncate keeps the first half of a frame, and at least one byte of it
+func truncate(frame string) string {
+	if len(frame) < 2 {
+		return frame
+	}
+	return frame[:len(frame)/2]
+}
@@ -0,0 +1,102 @@
+package socketio09
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultyConnScript(t *testing.T) {
+	a, b := NewPipe()
+	faulty := NewFaultyConn(a, Faults{
+		Script: func(dir Direction, n int, frame string) Fault {
+			if dir == Inbound {
+				return FaultNone
+			}
+			return []Fault{FaultDrop, FaultDuplicate, FaultTruncate, FaultNone, FaultClose}[n-1]
+		},
+	})
+
+	for _, frame := range []string{"3:::one", "3:::two", "3:::three", "3:::four"} {
+		if err := faulty.WriteMsg(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"3:::two", "3:::two", "3:::", "3:::four"} {
+		if got, _ := b.GetNextMsg(); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+	if err := faulty.WriteMsg("3:::five"); err != ErrorTransportClosed {
+		t.Fatalf("scripted close: %v", err)
+	}
+	if _, err := b.GetNextMsg(); err != ErrorTransportClosed {
+		t.Fatalf("other end still open: %v", err)
+	}
+}
+
+func TestFaultyConnRates(t *testing.T) {
+	a, b := NewPipe()
+	faulty := NewFaultyConn(a, Faults{
+		DropRate: 0.5,
+		Rand:     rand.New(rand.NewSource(1)),
+	})
+	for i := 0; i < 100; i++ {
+		b.WriteMsg("2::")
+	}
+	b.Close()
+
+	received := 0
+	for {
+		if _, err := faulty.GetNextMsg(); err != nil {
+			break
+		}
+		received++
+	}
+	if received < 25 || received > 75 {
+		t.Fatalf("%d of 100 frames got through a 50%% drop rate", received)
+	}
+}
+
+func TestFaultyConnDuplicateAndLatency(t *testing.T) {
+	a, b := NewPipe()
+	faulty := NewFaultyConn(a, Faults{
+		Latency:       20 * time.Millisecond,
+		DuplicateRate: 1,
+	})
+	b.WriteMsg("3:::hi")
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if got, _ := faulty.GetNextMsg(); got != "3:::hi" {
+			t.Fatalf("got %q", got)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("frame not delayed, took %s", elapsed)
+	}
+}
+
+func TestFaultyConnKeepsBatches(t *testing.T) {
+	a, b := NewPipe()
+	conn := &writeCountingConn{PipeConn: a}
+	faulty := NewFaultyConn(conn, Faults{
+		Script: func(dir Direction, n int, frame string) Fault {
+			return []Fault{FaultNone, FaultDrop, FaultDuplicate}[n-1]
+		},
+	})
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			b.GetNextMsg()
+		}
+	}()
+	if err := faulty.WriteMsgs([]string{"3:::one", "3:::two", "3:::three"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"3:::one", "3:::three", "3:::three"}
+	if len(conn.writes) != 1 || strings.Join(conn.writes[0], " ") != strings.Join(want, " ") {
+		t.Fatalf("batch written as %q, want one write of %q", conn.writes, want)
+	}
+}
@@ -51,18 +51,38 @@ func (c *HandlerCaller) getArgs() interface{} {
 	return reflect.New(c.Args).Interface()
 }
 
+/*
+NewArgs returns a pointer to a new zero value of the handler's argument type, ready to be
+decoded into and passed to Call. It returns nil for handlers without args.
+*/
+func (c *HandlerCaller) NewArgs() interface{} {
+	if !c.ArgsPresent {
+		return nil
+	}
+	return c.getArgs()
+}
+
 /*
 callFunc calls a handler with arguments
 */
 func (c *HandlerCaller) callFunc(h *SocketIOConnection, args interface{}) []reflect.Value {
+	return c.Call(h, args)
+}
+
+/*
+Call calls the handler with receiver as its first argument, which must be of the type the
+handler expects (a *SocketIOConnection for client handlers). args is a pointer to the
+handler's argument type, as made by NewArgs, or nil for the zero value.
+*/
+func (c *HandlerCaller) Call(receiver interface{}, args interface{}) []reflect.Value {
 	//nil is untyped, so use the default empty value of correct type
-	if args == nil {
+	if args == nil && c.ArgsPresent {
 		args = c.getArgs()
 	}
 
-	a := []reflect.Value{reflect.ValueOf(h), reflect.ValueOf(args).Elem()}
-	if !c.ArgsPresent {
-		a = a[0:1]
+	a := []reflect.Value{reflect.ValueOf(receiver)}
+	if c.ArgsPresent {
+		a = append(a, reflect.ValueOf(args).Elem())
 	}
 
 	return c.Func.Call(a)
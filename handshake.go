@@ -1,12 +1,12 @@
 package socketio09
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type handshakeResponse struct {
@@ -15,11 +15,13 @@ type handshakeResponse struct {
 	connectionTimeout int
 }
 
-func handshake(fullURL string) (hr handshakeResponse, err error) {
+func handshake(fullURL string, clock Clock) (hr handshakeResponse, err error) {
 	hr = handshakeResponse{}
-	timeToken := strconv.Itoa(int(time.Now().Unix()))
+	timeToken := strconv.Itoa(int(clock.Now().Unix()))
 	handshakeURL, _ := url.Parse(fullURL)
-	handshakeURL.Query().Set("t", timeToken)
+	query := handshakeURL.Query()
+	query.Set("t", timeToken)
+	handshakeURL.RawQuery = query.Encode()
 
 	resp, err := http.Get(handshakeURL.String())
 	if err != nil {
@@ -30,9 +32,15 @@ func handshake(fullURL string) (hr handshakeResponse, err error) {
 	if err != nil {
 		return hr, err
 	}
-	handshakeResponse := string(body[:len(body)])
+	handshakeResponse := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK {
+		return hr, fmt.Errorf("%w: %s: %s", ErrorHandshakeFailed, resp.Status, handshakeResponse)
+	}
 
 	handshakeParts := strings.Split(handshakeResponse, ":")
+	if len(handshakeParts) < 3 || handshakeParts[0] == "" {
+		return hr, fmt.Errorf("%w: malformed response %q", ErrorHandshakeFailed, handshakeResponse)
+	}
 	hr.token = handshakeParts[0]
 	hr.heartbeatTimeout, _ = strconv.Atoi(handshakeParts[1])
 	hr.connectionTimeout, _ = strconv.Atoi(handshakeParts[2])
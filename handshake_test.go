@@ -0,0 +1,31 @@
+package socketio09
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandshakeRefused(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusUnauthorized, "Unauthorized", "401 Unauthorized: Unauthorized"},
+		{http.StatusServiceUnavailable, "Service Unavailable", "503 Service Unavailable"},
+		{http.StatusOK, "nonsense", `malformed response "nonsense"`},
+	}
+	for _, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, test.body, test.status)
+		}))
+		_, err := NewConnection().Connect(ts.URL + "/socket.io/1")
+		ts.Close()
+		if !errors.Is(err, ErrorHandshakeFailed) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("handshake answered %d %q: got %v", test.status, test.body, err)
+		}
+	}
+}
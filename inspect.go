@@ -0,0 +1,142 @@
+package socketio09
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+var packetTypeNames = map[string]string{
+	spec.Disconnect:  "disconnect",
+	spec.Connect:     "connect",
+	spec.Heartbeat:   "heartbeat",
+	spec.TextMessage: "message",
+	spec.JSONMessage: "json",
+	spec.Event:       "event",
+	spec.Ack:         "ack",
+	spec.Error:       "error",
+	spec.Noop:        "noop",
+}
+
+/*
+PacketTypeName names a packet type, such as "event" for "5".
+*/
+func PacketTypeName(packetType string) string {
+	if name, exists := packetTypeNames[packetType]; exists {
+		return name
+	}
+	return "unknown(" + packetType + ")"
+}
+
+/*
+FormatFrame decodes a frame into a line which is easier to read than the wire format, naming
+its type and showing its id, endpoint, event name and args:
+
+	event id=1+ endpoint=/chat name="say" args=["hi"]
+	ack id=1 args=["pong"]
+
+Frames which do not parse are shown quoted, with the reason.
+*/
+func FormatFrame(frame string) string {
+	p, err := ParsePacket(frame)
+	if err != nil {
+		return fmt.Sprintf("invalid %q: %v", frame, err)
+	}
+
+	var b strings.Builder
+	b.WriteString(PacketTypeName(p.Type))
+	if p.ID != 0 {
+		b.WriteString(" id=" + strconv.Itoa(p.ID))
+		if p.AckRequested {
+			b.WriteByte('+')
+		}
+	}
+	if p.Endpoint != "" {
+		b.WriteString(" endpoint=" + p.Endpoint)
+	}
+
+	switch p.Type {
+	case spec.Event:
+		var event socketioEventMessage
+		if err := json.Unmarshal([]byte(p.Data), &event); err != nil {
+			fmt.Fprintf(&b, " data=%s", p.Data)
+			break
+		}
+		fmt.Fprintf(&b, " name=%q", event.Name)
+		if len(event.Args) != 0 {
+			fmt.Fprintf(&b, " args=%s", event.Args)
+		}
+	case spec.Ack:
+		ackID, args, _ := p.AckData()
+		b.WriteString(" id=" + strconv.Itoa(ackID))
+		if args != "" {
+			b.WriteString(" args=" + args)
+		}
+	case spec.Error:
+		reason, advice := p.ErrorData()
+		fmt.Fprintf(&b, " reason=%q", reason)
+		if advice != "" {
+			fmt.Fprintf(&b, " advice=%q", advice)
+		}
+	default:
+		if p.Data != "" {
+			fmt.Fprintf(&b, " data=%s", p.Data)
+		}
+	}
+	return b.String()
+}
+
+// debugLock keeps the lines of clients sharing a Debug writer apart
+var debugLock sync.Mutex
+
+/*
+OnRawInbound adds an observer called with every frame read from the connection, heartbeats and
+invalid frames included, before it is decoded. Observers are called in order on the reading
+goroutine, so they must be quick.
+*/
+func (c *SocketIOConnection) OnRawInbound(fn func(frame string)) {
+	c.rawLock.Lock()
+	defer c.rawLock.Unlock()
+	c.rawInbound = append(c.rawInbound, fn)
+}
+
+/*
+OnRawOutbound adds an observer called with every frame once it is written to the connection.
+Observers are called in order on the writing goroutine, so they must be quick.
+*/
+func (c *SocketIOConnection) OnRawOutbound(fn func(frame string)) {
+	c.rawLock.Lock()
+	defer c.rawLock.Unlock()
+	c.rawOutbound = append(c.rawOutbound, fn)
+}
+
+/*
+observe reports a frame read or written to the metrics, the debug writer and the raw observers
+*/
+func (c *SocketIOConnection) observe(dir Direction, frame string) {
+	c.rawLock.RLock()
+	observers := c.rawInbound
+	if dir == Outbound {
+		observers = c.rawOutbound
+	}
+	c.rawLock.RUnlock()
+
+	if dir == Outbound {
+		c.transport.metrics().FrameOut(packetType(frame), len(frame))
+	} else {
+		c.transport.metrics().FrameIn(packetType(frame), len(frame))
+	}
+	if c.transport != nil && c.transport.Debug != nil {
+		line := fmt.Sprintf("%-3s %s\n", dir, FormatFrame(frame))
+		debugLock.Lock()
+		c.transport.Debug.Write([]byte(line))
+		debugLock.Unlock()
+	}
+	for _, fn := range observers {
+		fn(frame)
+	}
+}
@@ -0,0 +1,87 @@
+package socketio09
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFormatFrame(t *testing.T) {
+	tests := []struct{ frame, want string }{
+		{"2::", "heartbeat"},
+		{"1::/chat", "connect endpoint=/chat"},
+		{`5:1+:/chat:{"name":"say","args":["hi"]}`, `event id=1+ endpoint=/chat name="say" args=["hi"]`},
+		{`5:::{"name":"ping"}`, `event name="ping"`},
+		{`6:::1+["pong"]`, `ack id=1 args=["pong"]`},
+		{"6:::2", "ack id=2"},
+		{"7:::unauthorized+reconnect", `error reason="unauthorized" advice="reconnect"`},
+		{"3:1::hello", "message id=1 data=hello"},
+	}
+	for _, test := range tests {
+		if got := FormatFrame(test.frame); got != test.want {
+			t.Errorf("FormatFrame(%q) = %q, want %q", test.frame, got, test.want)
+		}
+	}
+	if got := FormatFrame("9::"); !strings.HasPrefix(got, `invalid "9::"`) {
+		t.Errorf("FormatFrame of an invalid frame = %q", got)
+	}
+}
+
+type lockedBuffer struct {
+	bytes.Buffer
+	lock sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.Buffer.String()
+}
+
+func TestRawObservers(t *testing.T) {
+	debug := &lockedBuffer{}
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.Debug = debug
+	client := NewClient(clientEnd, transport)
+	defer client.Close()
+
+	inbound := make(chan string, 1)
+	outbound := make(chan string, 1)
+	client.OnRawInbound(func(frame string) { inbound <- frame })
+	client.OnRawOutbound(func(frame string) { outbound <- frame })
+
+	server.WriteMsg(`5:::{"name":"welcome","args":["hi"]}`)
+	select {
+	case frame := <-inbound:
+		if frame != `5:::{"name":"welcome","args":["hi"]}` {
+			t.Fatalf("inbound observer got %q", frame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("inbound observer not called")
+	}
+
+	client.Emit("say", "hello")
+	server.GetNextMsg()
+	select {
+	case frame := <-outbound:
+		if frame != `5:::{"name":"say","args":["hello"]}` {
+			t.Fatalf("outbound observer got %q", frame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("outbound observer not called")
+	}
+
+	want := "in  event name=\"welcome\" args=[\"hi\"]\nout event name=\"say\" args=[\"hello\"]\n"
+	if got := debug.String(); got != want {
+		t.Fatalf("debug output %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package socketio09
+
+/*
+Logger receives the log records of a client or a server, as a message and alternating keys and
+values. *slog.Logger implements it. Nothing is logged unless a Logger is set.
+
+	transport := socketio09.NewConnection()
+	transport.Logger = slog.Default()
+*/
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
+
+/*
+LoggerWith returns a Logger adding the keys and values of args to every record, such as the
+session id of a connection. A nil logger discards everything.
+*/
+func LoggerWith(logger Logger, args ...interface{}) Logger {
+	if logger == nil {
+		return discardLogger{}
+	}
+	if len(args) == 0 {
+		return logger
+	}
+	return attrLogger{logger: logger, attrs: args}
+}
+
+type attrLogger struct {
+	logger Logger
+	attrs  []interface{}
+}
+
+func (l attrLogger) with(args []interface{}) []interface{} {
+	return append(l.attrs[:len(l.attrs):len(l.attrs)], args...)
+}
+
+func (l attrLogger) Debug(msg string, args ...interface{}) { l.logger.Debug(msg, l.with(args)...) }
+func (l attrLogger) Info(msg string, args ...interface{})  { l.logger.Info(msg, l.with(args)...) }
+func (l attrLogger) Warn(msg string, args ...interface{})  { l.logger.Warn(msg, l.with(args)...) }
+func (l attrLogger) Error(msg string, args ...interface{}) { l.logger.Error(msg, l.with(args)...) }
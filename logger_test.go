@@ -0,0 +1,71 @@
+package socketio09
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	var logger Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	LoggerWith(logger, "sid", "abc").Warn("hello", "n", 1)
+	if line := buf.String(); !strings.Contains(line, "msg=hello") || !strings.Contains(line, "sid=abc n=1") {
+		t.Fatalf("unexpected record %q", line)
+	}
+
+	// a nil logger discards
+	LoggerWith(nil, "sid", "abc").Error("nothing")
+}
+
+type memoryLogger struct {
+	records []string
+	lock    sync.Mutex
+}
+
+func (l *memoryLogger) log(level, msg string) {
+	l.lock.Lock()
+	l.records = append(l.records, level+" "+msg)
+	l.lock.Unlock()
+}
+
+func (l *memoryLogger) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg) }
+func (l *memoryLogger) Info(msg string, args ...interface{})  { l.log("INFO", msg) }
+func (l *memoryLogger) Warn(msg string, args ...interface{})  { l.log("WARN", msg) }
+func (l *memoryLogger) Error(msg string, args ...interface{}) { l.log("ERROR", msg) }
+
+func (l *memoryLogger) has(record string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, r := range l.records {
+		if r == record {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClientLogger(t *testing.T) {
+	logger := &memoryLogger{}
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.Logger = logger
+	client := NewClient(clientEnd, transport)
+	client.On("number", func(c *SocketIOConnection, args []int) {})
+
+	server.WriteMsg(`5:::{"name":"number","args":["not a number"]}`)
+	server.WriteMsg(`6:::7+[]`)
+	server.WriteMsg(`9::`)
+
+	deadline := time.Now().Add(time.Second)
+	for !logger.has("WARN event args do not fit the handler") || !logger.has("DEBUG ack nobody is waiting for, it may have timed out") ||
+		!logger.has("ERROR invalid inbound frame") || !logger.has("INFO connection closed") {
+		if time.Now().After(deadline) {
+			t.Fatalf("missing records in %q", logger.records)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
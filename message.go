@@ -1,5 +1,7 @@
 package socketio09
 
+import "encoding/json"
+
 /*
 Message is an internal construct representing a socket.io protocol 0.9 frame message
 container (inbound or outbound).
@@ -7,10 +9,19 @@ container (inbound or outbound).
 type Message struct {
 	// Type is the socket.io 0.9 specificiation message type
 	Type string
-	// AckID will be present on an ack response only
+	// AckID is the id being acknowledged on an ack (Type=6). On other messages it is the
+	// message id, which asks the other side for an ack.
 	AckID int
+	// AckRequested is the `+` after the id of an inbound event, asking for an ack carrying the
+	// handler's result rather than a plain one. Outbound events with an AckID always ask for it.
+	AckRequested bool
+	// Endpoint is the socket the message belongs to, empty for the default one
+	Endpoint string
 	// EventName is for events (Type=5), to or from, where this is the `"name": "some event name"`
 	EventName string
-	// Args will be a JSON array in socket.io protocol
-	Args string
+	// Args will be a JSON array in socket.io protocol. It is kept encoded, so it is only decoded
+	// once, straight into the handler's argument type.
+	Args json.RawMessage
+	// Data is the raw payload of text (3), JSON (4) and error (7) messages
+	Data string
 }
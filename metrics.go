@@ -0,0 +1,57 @@
+package socketio09
+
+import "time"
+
+/*
+Metrics observes a client, so its activity can be exported to any metrics system without the
+library depending on one. Methods are called synchronously from the client's goroutines, and
+must be quick and safe for concurrent use. Embed NopMetrics to implement only some of them.
+*/
+type Metrics interface {
+	// FrameIn is called for each frame read, with its packet type, such as "5" for events
+	FrameIn(packetType string, bytes int)
+	// FrameOut is called for each frame written
+	FrameOut(packetType string, bytes int)
+	// AckRoundTrip is called when the ack of an EmitWithAck arrives
+	AckRoundTrip(d time.Duration)
+	// AckTimeout is called when EmitWithAck gives up waiting
+	AckTimeout()
+	// QueueDepth is called with the number of frames waiting to be written, before each write
+	QueueDepth(depth int)
+	// Handshake is called when a handshake completes or fails
+	Handshake(d time.Duration, err error)
+	// Reconnect is for code which reconnects a client with Connect to report its attempts,
+	// the client itself does not reconnect
+	Reconnect(attempt int)
+	// Disconnect is called once when the connection closes, with one of the Reason constants
+	Disconnect(reason string)
+}
+
+/*
+NopMetrics ignores everything.
+*/
+type NopMetrics struct{}
+
+func (NopMetrics) FrameIn(string, int)            {}
+func (NopMetrics) FrameOut(string, int)           {}
+func (NopMetrics) AckRoundTrip(time.Duration)     {}
+func (NopMetrics) AckTimeout()                    {}
+func (NopMetrics) QueueDepth(int)                 {}
+func (NopMetrics) Handshake(time.Duration, error) {}
+func (NopMetrics) Reconnect(int)                  {}
+func (NopMetrics) Disconnect(string)              {}
+
+func (wst *WebsocketTransport) metrics() Metrics {
+	if wst == nil || wst.Metrics == nil {
+		return NopMetrics{}
+	}
+	return wst.Metrics
+}
+
+// packetType is the type of a frame, its first byte
+func packetType(frame string) string {
+	if frame == "" {
+		return ""
+	}
+	return frame[:1]
+}
@@ -0,0 +1,150 @@
+package socketio09
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	NopMetrics
+	lock        sync.Mutex
+	in, out     []string
+	rtts        []time.Duration
+	timeouts    int
+	disconnects []string
+}
+
+func (m *recordingMetrics) FrameIn(packetType string, bytes int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.in = append(m.in, packetType)
+}
+
+func (m *recordingMetrics) FrameOut(packetType string, bytes int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.out = append(m.out, packetType)
+}
+
+func (m *recordingMetrics) AckRoundTrip(d time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.rtts = append(m.rtts, d)
+}
+
+func (m *recordingMetrics) AckTimeout() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.timeouts++
+}
+
+func (m *recordingMetrics) Disconnect(reason string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.disconnects = append(m.disconnects, reason)
+}
+
+func TestMetrics(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	metrics := &recordingMetrics{}
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.ReceiveTimeout = time.Minute
+	transport.Clock = clock
+	transport.Metrics = metrics
+	client := NewClient(clientEnd, transport)
+	disconnected := make(chan struct{})
+	client.On(OnDisconnect, func(c *SocketIOConnection) {
+		close(disconnected)
+	})
+
+	go func() {
+		server.GetNextMsg()
+		clock.Advance(3 * time.Second)
+		server.WriteMsg(`6:::1+["pong"]`)
+	}()
+	if _, err := client.EmitWithAck("ping", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		server.GetNextMsg()
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}()
+	if _, err := client.EmitWithAck("ping", nil); err != ErrorSendTimeout {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+
+	server.WriteMsg("0::")
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("client not disconnected")
+	}
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	if len(metrics.rtts) != 1 || metrics.rtts[0] != 3*time.Second {
+		t.Fatalf("unexpected round trips %v", metrics.rtts)
+	}
+	if metrics.timeouts != 1 {
+		t.Fatalf("got %d timeouts, want 1", metrics.timeouts)
+	}
+	if len(metrics.out) != 2 || metrics.out[0] != "5" || len(metrics.in) != 2 || metrics.in[0] != "6" {
+		t.Fatalf("unexpected frames in %v, out %v", metrics.in, metrics.out)
+	}
+	if len(metrics.disconnects) != 1 || metrics.disconnects[0] != ReasonServerDisconnect {
+		t.Fatalf("unexpected disconnects %v", metrics.disconnects)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "read tcp 127.0.0.1:4500->127.0.0.1:53122: i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestDisconnectReason(t *testing.T) {
+	tests := []struct {
+		args []interface{}
+		want string
+	}{
+		{nil, ReasonClosed},
+		{[]interface{}{ReasonClientDisconnect}, ReasonClientDisconnect},
+		{[]interface{}{readErrorReason(timeoutError{}), timeoutError{}}, ReasonHeartbeatTimeout},
+		{[]interface{}{readErrorReason(ErrorTransportClosed), ErrorTransportClosed}, ReasonReadError},
+		{[]interface{}{ErrorSocketOverflood}, ReasonOverflood},
+		{[]interface{}{timeoutError{}}, ReasonClosed},
+	}
+	for _, test := range tests {
+		if reason, _ := disconnectReason(test.args); reason != test.want {
+			t.Errorf("reason of %v is %q, want %q", test.args, reason, test.want)
+		}
+	}
+}
+
+func TestDisconnectOnReadError(t *testing.T) {
+	metrics := &recordingMetrics{}
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.Metrics = metrics
+	client := NewClient(clientEnd, transport)
+	disconnected := make(chan struct{})
+	client.On(OnDisconnect, func(c *SocketIOConnection) {
+		close(disconnected)
+	})
+
+	server.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("client not disconnected")
+	}
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+	if len(metrics.disconnects) != 1 || metrics.disconnects[0] != ReasonReadError {
+		t.Fatalf("unexpected disconnects %v", metrics.disconnects)
+	}
+}
@@ -0,0 +1,244 @@
+package socketio09
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+/*
+Packet is a single socket.io 0.9 frame, split into the fields of the wire format:
+
+	[message type] ':' [message id ('+')] ':' [message endpoint] (':' [message data])
+
+ParsePacket and Packet.Encode are exact inverses for every packet Encode accepts. Frames which
+ParsePacket tolerates but are not canonical (a bare `0`, `2` or `8`, or a trailing `:` with no
+data) are encoded back in their canonical `type::` form.
+*/
+type Packet struct {
+	// Type is one of the spec message types, "0" through "8"
+	Type string
+	// ID is the message id, or 0 when the frame has none. Ids start at 1.
+	ID int
+	// AckRequested is the `+` after the id, meaning the ack is handled by the user
+	AckRequested bool
+	// Endpoint is the socket the packet belongs to, including any `?query` on a connect
+	Endpoint string
+	// Data is everything after the third `:`, left encoded. For an ack it is
+	// [acked id] ('+' [json args]), for an error it is [reason] ('+' [advice]).
+	Data string
+}
+
+/*
+PacketError describes why a frame could not be parsed, and at which byte offset of the frame
+the problem was found. It unwraps to ErrorProtocolReceivedInvalidPacket.
+*/
+type PacketError struct {
+	Frame  string
+	Offset int
+	Reason string
+}
+
+func (e *PacketError) Error() string {
+	return fmt.Sprintf("%s: %s at offset %d of %q", ErrorProtocolReceivedInvalidPacket, e.Reason, e.Offset, e.Frame)
+}
+
+// Unwrap allows errors.Is(err, ErrorProtocolReceivedInvalidPacket)
+func (e *PacketError) Unwrap() error {
+	return ErrorProtocolReceivedInvalidPacket
+}
+
+func isPacketType(t string) bool {
+	return len(t) == 1 && t[0] >= spec.Disconnect[0] && t[0] <= spec.Noop[0]
+}
+
+/*
+ParsePacket splits a raw frame into a *Packet, validating it against the 0.9 spec. Errors are
+always a *PacketError.
+*/
+func ParsePacket(frame string) (*Packet, error) {
+	fail := func(offset int, reason string) (*Packet, error) {
+		return nil, &PacketError{Frame: frame, Offset: offset, Reason: reason}
+	}
+
+	if len(frame) == 0 {
+		return fail(0, "empty frame")
+	}
+	p := &Packet{Type: frame[0:1]}
+	if !isPacketType(p.Type) {
+		return fail(0, "unknown message type")
+	}
+
+	// `0`, `2` and `8` may be sent with no other fields at all
+	if len(frame) == 1 {
+		switch p.Type {
+		case spec.Disconnect, spec.Heartbeat, spec.Noop:
+			return p, nil
+		}
+		return fail(1, "expected ':' after message type")
+	}
+	if frame[1] != ':' {
+		return fail(1, "expected ':' after message type")
+	}
+
+	// message id, with optional trailing `+`
+	idStart := 2
+	idEnd := strings.IndexByte(frame[idStart:], ':')
+	if idEnd == -1 {
+		return fail(len(frame), "expected ':' after message id")
+	}
+	idEnd += idStart
+	id := frame[idStart:idEnd]
+	if strings.HasSuffix(id, "+") {
+		p.AckRequested = true
+		id = id[:len(id)-1]
+		if id == "" {
+			return fail(idStart, "'+' without a message id")
+		}
+	}
+	if id != "" {
+		n, ok := parsePacketID(id)
+		if !ok {
+			return fail(idStart, "message id must be a positive integer")
+		}
+		p.ID = n
+	}
+
+	// endpoint, then everything left is data
+	endpointStart := idEnd + 1
+	dataSep := strings.IndexByte(frame[endpointStart:], ':')
+	if dataSep == -1 {
+		p.Endpoint = frame[endpointStart:]
+	} else {
+		p.Endpoint = frame[endpointStart : endpointStart+dataSep]
+		p.Data = frame[endpointStart+dataSep+1:]
+	}
+	dataStart := endpointStart + len(p.Endpoint) + 1
+
+	switch p.Type {
+	case spec.Event:
+		if p.Data == "" {
+			return fail(dataStart, "event without data")
+		}
+	case spec.Ack:
+		if id != "" || p.AckRequested {
+			return fail(idStart, "ack must not have a message id")
+		}
+		if _, _, err := splitAckData(p.Data); err != nil {
+			return fail(dataStart, err.Error())
+		}
+	case spec.Error:
+		if id != "" || p.AckRequested {
+			return fail(idStart, "error must not have a message id")
+		}
+	}
+
+	return p, nil
+}
+
+/*
+Encode turns the packet back into a frame. It refuses packets which ParsePacket would not
+accept, so whatever it returns parses back to an identical Packet.
+*/
+func (p *Packet) Encode() (string, error) {
+	if !isPacketType(p.Type) {
+		return "", ErrorProtocolUnexpectedOutboundMessageType
+	}
+	if p.ID < 0 || (p.AckRequested && p.ID == 0) {
+		return "", fmt.Errorf("%s: invalid message id %d", ErrorProtocolUnexpectedOutboundMessageType, p.ID)
+	}
+	if strings.IndexByte(p.Endpoint, ':') != -1 {
+		return "", fmt.Errorf("%s: endpoint %q contains ':'", ErrorProtocolUnexpectedOutboundMessageType, p.Endpoint)
+	}
+	switch p.Type {
+	case spec.Event:
+		if p.Data == "" {
+			return "", fmt.Errorf("%s: event without data", ErrorProtocolUnexpectedOutboundMessageType)
+		}
+	case spec.Ack, spec.Error:
+		if p.ID != 0 {
+			return "", fmt.Errorf("%s: message id on type %s", ErrorProtocolUnexpectedOutboundMessageType, p.Type)
+		}
+		if p.Type == spec.Ack {
+			if _, _, err := splitAckData(p.Data); err != nil {
+				return "", fmt.Errorf("%s: %s", ErrorProtocolUnexpectedOutboundMessageType, err)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(p.Type)
+	b.WriteByte(':')
+	if p.ID != 0 {
+		b.WriteString(strconv.Itoa(p.ID))
+		if p.AckRequested {
+			b.WriteByte('+')
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(p.Endpoint)
+	if p.Data != "" {
+		b.WriteByte(':')
+		b.WriteString(p.Data)
+	}
+	return b.String(), nil
+}
+
+/*
+AckData splits the data of an ack packet into the id being acknowledged and its JSON encoded
+args, which are empty for a simple acknowledgement.
+*/
+func (p *Packet) AckData() (ackID int, args string, err error) {
+	if p.Type != spec.Ack {
+		return 0, "", ErrorProtocolReceivedInvalidPacket
+	}
+	ackID, args, err = splitAckData(p.Data)
+	if err != nil {
+		return 0, "", &PacketError{Frame: p.Data, Reason: err.Error()}
+	}
+	return ackID, args, nil
+}
+
+/*
+ErrorData splits the data of an error packet into its reason and advice.
+*/
+func (p *Packet) ErrorData() (reason, advice string) {
+	if i := strings.IndexByte(p.Data, '+'); i != -1 {
+		return p.Data[:i], p.Data[i+1:]
+	}
+	return p.Data, ""
+}
+
+func splitAckData(data string) (ackID int, args string, err error) {
+	id := data
+	if i := strings.IndexByte(data, '+'); i != -1 {
+		id, args = data[:i], data[i+1:]
+	}
+	ackID, ok := parsePacketID(id)
+	if !ok {
+		return 0, "", fmt.Errorf("acked message id must be a positive integer")
+	}
+	return ackID, args, nil
+}
+
+/*
+parsePacketID only accepts the canonical form of a positive integer, so that ids survive a
+round trip unchanged.
+*/
+func parsePacketID(s string) (int, bool) {
+	if s == "" || s[0] == '0' {
+		return 0, false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
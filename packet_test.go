@@ -0,0 +1,106 @@
+package socketio09
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+func TestParsePacketAllTypes(t *testing.T) {
+	tests := []struct {
+		frame string
+		want  Packet
+	}{
+		{"0::/test", Packet{Type: spec.Disconnect, Endpoint: "/test"}},
+		{"1::/test?my=param", Packet{Type: spec.Connect, Endpoint: "/test?my=param"}},
+		{"2::", Packet{Type: spec.Heartbeat}},
+		{"3:1::blabla", Packet{Type: spec.TextMessage, ID: 1, Data: "blabla"}},
+		{`4:1::{"a":"b"}`, Packet{Type: spec.JSONMessage, ID: 1, Data: `{"a":"b"}`}},
+		{`5:2+:/chat:{"name":"a","args":[]}`, Packet{Type: spec.Event, ID: 2, AckRequested: true, Endpoint: "/chat", Data: `{"name":"a","args":[]}`}},
+		{"6:::4", Packet{Type: spec.Ack, Data: "4"}},
+		{`6:::4+["A","B"]`, Packet{Type: spec.Ack, Data: `4+["A","B"]`}},
+		{"7::/chat:unauthorized+reconnect", Packet{Type: spec.Error, Endpoint: "/chat", Data: "unauthorized+reconnect"}},
+		{"8::", Packet{Type: spec.Noop}},
+		{"3:::a:b:c", Packet{Type: spec.TextMessage, Data: "a:b:c"}},
+	}
+	for _, tt := range tests {
+		p, err := ParsePacket(tt.frame)
+		if err != nil {
+			t.Errorf("ParsePacket(%q) failed: %v", tt.frame, err)
+			continue
+		}
+		if !reflect.DeepEqual(*p, tt.want) {
+			t.Errorf("ParsePacket(%q) = %+v, want %+v", tt.frame, *p, tt.want)
+		}
+		encoded, err := p.Encode()
+		if err != nil {
+			t.Errorf("Encode(%q) failed: %v", tt.frame, err)
+			continue
+		}
+		if encoded != tt.frame {
+			t.Errorf("round trip of %q gave %q", tt.frame, encoded)
+		}
+	}
+}
+
+func TestParsePacketShortFormsEncodeCanonically(t *testing.T) {
+	for frame, want := range map[string]string{"0": "0::", "2": "2::", "8": "8::", "3:::": "3::"} {
+		p, err := ParsePacket(frame)
+		if err != nil {
+			t.Fatalf("ParsePacket(%q) failed: %v", frame, err)
+		}
+		if got, _ := p.Encode(); got != want {
+			t.Errorf("Encode(ParsePacket(%q)) = %q, want %q", frame, got, want)
+		}
+	}
+}
+
+func TestParsePacketReportsOffset(t *testing.T) {
+	tests := []struct {
+		frame  string
+		offset int
+	}{
+		{"", 0},
+		{"9::", 0},
+		{"5", 1},
+		{"5-::", 1},
+		{"5:01::{}", 2},
+		{"5:x::{}", 2},
+		{"5:1", 3},
+		{"5:::", 4},
+		{"6:1::4", 2},
+		{"6:::a+[]", 4},
+		{"6::/x:", 6},
+	}
+	for _, tt := range tests {
+		_, err := ParsePacket(tt.frame)
+		var perr *PacketError
+		if !errors.As(err, &perr) {
+			t.Errorf("ParsePacket(%q) err = %v, want *PacketError", tt.frame, err)
+			continue
+		}
+		if perr.Offset != tt.offset {
+			t.Errorf("ParsePacket(%q) offset = %d, want %d (%v)", tt.frame, perr.Offset, tt.offset, err)
+		}
+		if !errors.Is(err, ErrorProtocolReceivedInvalidPacket) {
+			t.Errorf("ParsePacket(%q) should unwrap to ErrorProtocolReceivedInvalidPacket", tt.frame)
+		}
+	}
+}
+
+func TestPacketEncodeRejectsInvalid(t *testing.T) {
+	for _, p := range []Packet{
+		{Type: "9"},
+		{Type: spec.Event, Data: ""},
+		{Type: spec.Event, AckRequested: true, Data: "{}"},
+		{Type: spec.Ack, ID: 1, Data: "1"},
+		{Type: spec.Ack, Data: "x"},
+		{Type: spec.Connect, Endpoint: "/a:b"},
+	} {
+		if s, err := p.Encode(); err == nil {
+			t.Errorf("Encode(%+v) = %q, want error", p, s)
+		}
+	}
+}
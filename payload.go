@@ -0,0 +1,99 @@
+package socketio09
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+payloadDelimiter is the `�` rune which surrounds the length of each frame when several
+frames are sent together over a transport that has no framing of its own.
+*/
+const payloadDelimiter = "�"
+
+/*
+EncodePayload joins frames using the socket.io 0.9 multi-message framing:
+
+	`�` [message length] `�` [message] ...
+
+The length is counted the same way the JavaScript implementation counts string length (see
+payloadLength), not in bytes. A single frame is returned untouched, because framing is only required when more
+than one message is delivered at a time.
+*/
+func EncodePayload(frames []string) string {
+	if len(frames) == 1 {
+		return frames[0]
+	}
+
+	var b strings.Builder
+	for _, f := range frames {
+		b.WriteString(payloadDelimiter)
+		b.WriteString(strconv.Itoa(payloadLength(f)))
+		b.WriteString(payloadDelimiter)
+		b.WriteString(f)
+	}
+	return b.String()
+}
+
+/*
+DecodePayload splits a multi-message payload back into its frames. Data which does not start
+with the `�` delimiter is a single frame and is returned as is.
+*/
+func DecodePayload(data string) ([]string, error) {
+	if !strings.HasPrefix(data, payloadDelimiter) {
+		return []string{data}, nil
+	}
+
+	var frames []string
+	rest := data
+	for len(rest) > 0 {
+		if !strings.HasPrefix(rest, payloadDelimiter) {
+			return frames, ErrorProtocolInvalidPayload
+		}
+		rest = rest[len(payloadDelimiter):]
+
+		end := strings.Index(rest, payloadDelimiter)
+		if end == -1 {
+			return frames, ErrorProtocolInvalidPayload
+		}
+		length, err := strconv.Atoi(rest[:end])
+		if err != nil || length < 0 {
+			return frames, ErrorProtocolInvalidPayload
+		}
+		rest = rest[end+len(payloadDelimiter):]
+
+		// walk the frame rune by rune, since the length is not a byte count
+		size := 0
+		for counted := 0; counted < length; {
+			if size >= len(rest) {
+				return frames, ErrorProtocolInvalidPayload
+			}
+			r, w := utf8.DecodeRuneInString(rest[size:])
+			size += w
+			counted += runeLength(r)
+		}
+		frames = append(frames, rest[:size])
+		rest = rest[size:]
+	}
+	return frames, nil
+}
+
+/*
+payloadLength is the length of a frame as the JavaScript side sees it, which is the number of
+UTF-16 code units. Characters outside the basic multilingual plane count twice.
+*/
+func payloadLength(frame string) int {
+	n := 0
+	for _, r := range frame {
+		n += runeLength(r)
+	}
+	return n
+}
+
+func runeLength(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,37 @@
+package socketio09
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodePayloadFramesMultipleMessages(t *testing.T) {
+	got := EncodePayload([]string{"2::", `5:::{"name":"a","args":[]}`})
+	want := "�3�2::�26�5:::{\"name\":\"a\",\"args\":[]}"
+	if got != want {
+		t.Fatalf("EncodePayload() = %q, want %q", got, want)
+	}
+
+	if single := EncodePayload([]string{"2::"}); single != "2::" {
+		t.Fatalf("single frame should not be framed, got %q", single)
+	}
+}
+
+func TestDecodePayloadRoundTrip(t *testing.T) {
+	frames := []string{"2::", `5:::{"name":"é","args":["😀"]}`, "8::"}
+	got, err := DecodePayload(EncodePayload(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, frames) {
+		t.Fatalf("DecodePayload() = %q, want %q", got, frames)
+	}
+}
+
+func TestDecodePayloadRejectsBrokenFraming(t *testing.T) {
+	for _, data := range []string{"�3", "�x�2::", "�9�2::", "�3�2::junk"} {
+		if _, err := DecodePayload(data); err != ErrorProtocolInvalidPayload {
+			t.Errorf("DecodePayload(%q) err = %v, want ErrorProtocolInvalidPayload", data, err)
+		}
+	}
+}
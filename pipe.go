@@ -0,0 +1,102 @@
+package socketio09
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+PipeConn is one end of an in-memory connection made by NewPipe. Frames written to one end are
+read from the other, in order. Closing either end closes both.
+*/
+type PipeConn struct {
+	in   <-chan string
+	out  chan<- string
+	pipe *pipe
+}
+
+type pipe struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+/*
+NewPipe returns both ends of an in-memory connection. Each direction buffers as many frames as a
+client's outbound queue.
+*/
+func NewPipe() (a, b *PipeConn) {
+	p := &pipe{closed: make(chan struct{})}
+	aToB := make(chan string, queueMaxSize)
+	bToA := make(chan string, queueMaxSize)
+	return &PipeConn{in: bToA, out: aToB, pipe: p}, &PipeConn{in: aToB, out: bToA, pipe: p}
+}
+
+/*
+GetNextMsg returns the next frame written to the other end. Frames written before the pipe
+was closed are still returned.
+*/
+func (pc *PipeConn) GetNextMsg() (string, error) {
+	select {
+	case message := <-pc.in:
+		return message, nil
+	default:
+	}
+	select {
+	case message := <-pc.in:
+		return message, nil
+	case <-pc.pipe.closed:
+		return "", ErrorTransportClosed
+	}
+}
+
+// WriteMsg sends a frame to the other end
+func (pc *PipeConn) WriteMsg(message string) error {
+	if len(message) == 0 {
+		return ErrorTransportEmptyPacket
+	}
+	select {
+	case <-pc.pipe.closed:
+		return ErrorTransportClosed
+	default:
+	}
+	select {
+	case pc.out <- message:
+		return nil
+	case <-pc.pipe.closed:
+		return ErrorTransportClosed
+	}
+}
+
+// WriteMsgs sends several frames to the other end, each as a message of its own
+func (pc *PipeConn) WriteMsgs(messages []string) error {
+	for _, message := range messages {
+		if err := pc.WriteMsg(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes both ends
+func (pc *PipeConn) Close() {
+	pc.pipe.closeOnce.Do(func() {
+		close(pc.pipe.closed)
+	})
+}
+
+/*
+NewTestConnection runs a client over a PipeConn, with the same inbound and outbound loops as a
+connected client but no network. The test feeds frames to the client by writing them to the
+returned end, and reads what the client writes from it. No heartbeats are sent, and
+EmitWithAck waits up to five seconds.
+
+	client, server := socketio09.NewTestConnection()
+	client.On("welcome", func(c *socketio09.SocketIOConnection, args []string) {})
+	server.WriteMsg(`5:::{"name":"welcome","args":["hi"]}`)
+*/
+func NewTestConnection() (client *SocketIOClient, server *PipeConn) {
+	clientEnd, serverEnd := NewPipe()
+	transport := NewConnection()
+	transport.ReceiveTimeout = 5 * time.Second
+	return NewClient(clientEnd, transport), serverEnd
+}
@@ -0,0 +1,75 @@
+package socketio09
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	a, b := NewPipe()
+	a.WriteMsgs([]string{"1::", "2::"})
+	for _, want := range []string{"1::", "2::"} {
+		if got, err := b.GetNextMsg(); err != nil || got != want {
+			t.Fatalf("got %q %v, want %q", got, err, want)
+		}
+	}
+
+	b.WriteMsg("3:::hi")
+	b.Close()
+	if got, err := a.GetNextMsg(); err != nil || got != "3:::hi" {
+		t.Fatalf("frame written before close lost: %q %v", got, err)
+	}
+	if _, err := a.GetNextMsg(); err != ErrorTransportClosed {
+		t.Fatalf("read after close: %v", err)
+	}
+	if err := a.WriteMsg("2::"); err != ErrorTransportClosed {
+		t.Fatalf("write after close: %v", err)
+	}
+}
+
+func TestTestConnection(t *testing.T) {
+	client, server := NewTestConnection()
+	welcomed := make(chan string, 1)
+	client.On("welcome", func(c *SocketIOConnection, args []string) {
+		welcomed <- args[0]
+	})
+	disconnected := make(chan struct{})
+	client.On(OnDisconnect, func(c *SocketIOConnection) {
+		close(disconnected)
+	})
+
+	server.WriteMsg(`5:::{"name":"welcome","args":["hi"]}`)
+	select {
+	case got := <-welcomed:
+		if got != "hi" {
+			t.Fatalf("handler got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler not called")
+	}
+
+	client.Emit("say", "hello")
+	if frame, _ := server.GetNextMsg(); frame != `5:::{"name":"say","args":["hello"]}` {
+		t.Fatalf("client wrote %q", frame)
+	}
+
+	results := make(chan string, 1)
+	go func() {
+		result, _ := client.EmitWithAck("ping", "x")
+		results <- result
+	}()
+	if frame, _ := server.GetNextMsg(); frame != `5:1+::{"name":"ping","args":["x"]}` {
+		t.Fatalf("client wrote %q", frame)
+	}
+	server.WriteMsg(`6:::1+["pong"]`)
+	if result := <-results; result != `["pong"]` {
+		t.Fatalf("ack carried %q", result)
+	}
+
+	server.WriteMsg("0::")
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("disconnect not handled")
+	}
+}
@@ -0,0 +1,287 @@
+package socketio09
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+// MarshalText encodes the direction as "in" or "out"
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes "in" or "out"
+func (d *Direction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "in":
+		*d = Inbound
+	case "out":
+		*d = Outbound
+	default:
+		return fmt.Errorf("unknown direction %q", text)
+	}
+	return nil
+}
+
+/*
+RecordedFrame is one line of a recording.
+*/
+type RecordedFrame struct {
+	Time      time.Time `json:"time"`
+	Direction Direction `json:"dir"`
+	Frame     string    `json:"frame"`
+}
+
+/*
+RecordingConn wraps a Conn and records every frame read or written, with a timestamp, as a line
+of JSON. Recordings are read back with ReadRecording and replayed with ReplayConn.
+
+	f, _ := os.Create("session.jsonl")
+	recorder := socketio09.NewRecordingConn(conn, f)
+*/
+type RecordingConn struct {
+	conn Conn
+
+	w    io.Writer
+	err  error
+	lock sync.Mutex
+}
+
+// NewRecordingConn records the frames of conn to w
+func NewRecordingConn(conn Conn, w io.Writer) *RecordingConn {
+	return &RecordingConn{conn: conn, w: w}
+}
+
+func (rc *RecordingConn) record(dir Direction, frame string) {
+	line, err := json.Marshal(RecordedFrame{Time: time.Now(), Direction: dir, Frame: frame})
+	if err != nil {
+		return
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.err == nil {
+		_, rc.err = rc.w.Write(append(line, '\n'))
+	}
+}
+
+/*
+Err returns the first error writing the recording. Recording stops after it, while the
+connection carries on.
+*/
+func (rc *RecordingConn) Err() error {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	return rc.err
+}
+
+// GetNextMsg reads and records a frame
+func (rc *RecordingConn) GetNextMsg() (string, error) {
+	frame, err := rc.conn.GetNextMsg()
+	if err == nil {
+		rc.record(Inbound, frame)
+	}
+	return frame, err
+}
+
+// WriteMsg records and writes a frame
+func (rc *RecordingConn) WriteMsg(message string) error {
+	rc.record(Outbound, message)
+	return rc.conn.WriteMsg(message)
+}
+
+// WriteMsgs records and writes several frames
+func (rc *RecordingConn) WriteMsgs(messages []string) error {
+	for _, message := range messages {
+		rc.record(Outbound, message)
+	}
+	return rc.conn.WriteMsgs(messages)
+}
+
+// Close closes the wrapped connection
+func (rc *RecordingConn) Close() {
+	rc.conn.Close()
+}
+
+/*
+ReadRecording reads the frames written by a RecordingConn.
+*/
+func ReadRecording(r io.Reader) ([]RecordedFrame, error) {
+	var frames []RecordedFrame
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, wsDefaultBufferSize), 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame RecordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, scanner.Err()
+}
+
+/*
+ReplayConn plays the inbound frames of a recording to a client, and checks the frames the client
+writes against the outbound frames of the recording, in order. Heartbeats depend on timing, so
+outbound heartbeats are neither expected nor checked.
+
+Inbound frames keep their original spacing divided by speed, counted from NewReplayConn; a speed
+of zero delivers them without delay. Once they are all delivered, reads block until Close.
+
+	frames, _ := socketio09.ReadRecording(f)
+	replay := socketio09.NewReplayConn(frames, 10)
+	client := socketio09.NewClient(replay, socketio09.NewConnection())
+	if err := replay.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+*/
+type ReplayConn struct {
+	inbound  []RecordedFrame
+	outbound []string
+	start    time.Time
+	speed    float64
+
+	next     int // index of the next inbound frame
+	written  int // outbound frames matched so far
+	mismatch error
+	lock     sync.Mutex
+	progress chan struct{}
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReplayConn replays frames at speed
+func NewReplayConn(frames []RecordedFrame, speed float64) *ReplayConn {
+	rc := &ReplayConn{
+		start:    time.Now(),
+		speed:    speed,
+		progress: make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	for _, frame := range frames {
+		switch {
+		case frame.Direction == Inbound:
+			rc.inbound = append(rc.inbound, frame)
+		case frame.Frame != spec.Heartbeat+"::":
+			rc.outbound = append(rc.outbound, frame.Frame)
+		}
+	}
+	return rc
+}
+
+// GetNextMsg returns the next inbound frame of the recording once it is due
+func (rc *ReplayConn) GetNextMsg() (string, error) {
+	rc.lock.Lock()
+	if rc.next == len(rc.inbound) {
+		rc.lock.Unlock()
+		<-rc.closed
+		return "", ErrorTransportClosed
+	}
+	frame := rc.inbound[rc.next]
+	rc.next++
+	rc.lock.Unlock()
+
+	if rc.speed > 0 {
+		offset := time.Duration(float64(frame.Time.Sub(rc.inbound[0].Time)) / rc.speed)
+		timer := time.NewTimer(time.Until(rc.start.Add(offset)))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-rc.closed:
+			return "", ErrorTransportClosed
+		}
+	}
+	select {
+	case <-rc.closed:
+		return "", ErrorTransportClosed
+	default:
+		return frame.Frame, nil
+	}
+}
+
+// WriteMsg checks a frame written by the client against the recording
+func (rc *ReplayConn) WriteMsg(message string) error {
+	select {
+	case <-rc.closed:
+		return ErrorTransportClosed
+	default:
+	}
+	if message == spec.Heartbeat+"::" {
+		return nil
+	}
+
+	rc.lock.Lock()
+	switch {
+	case rc.mismatch != nil:
+	case rc.written == len(rc.outbound):
+		rc.mismatch = fmt.Errorf("%w: unexpected frame %q after the recording ended", ErrorReplayMismatch, message)
+	case rc.outbound[rc.written] != message:
+		rc.mismatch = fmt.Errorf("%w: frame %d is %q, recorded %q",
+			ErrorReplayMismatch, rc.written+1, message, rc.outbound[rc.written])
+	default:
+		rc.written++
+	}
+	rc.lock.Unlock()
+
+	select {
+	case rc.progress <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// WriteMsgs checks several frames
+func (rc *ReplayConn) WriteMsgs(messages []string) error {
+	for _, message := range messages {
+		if err := rc.WriteMsg(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Wait returns once the client has written every recorded outbound frame, or wrote one which
+differs from the recording, or timeout elapsed. It returns an error wrapping ErrorReplayMismatch
+in the last two cases.
+*/
+func (rc *ReplayConn) Wait(timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		rc.lock.Lock()
+		mismatch, written := rc.mismatch, rc.written
+		rc.lock.Unlock()
+
+		if mismatch != nil {
+			return mismatch
+		}
+		if written == len(rc.outbound) {
+			return nil
+		}
+		select {
+		case <-rc.progress:
+		case <-deadline.C:
+			return fmt.Errorf("%w: %d of %d recorded frames written, next is %q",
+				ErrorReplayMismatch, written, len(rc.outbound), rc.outbound[written])
+		}
+	}
+}
+
+// Close ends the replay
+func (rc *ReplayConn) Close() {
+	rc.closeOnce.Do(func() {
+		close(rc.closed)
+	})
+}
@@ -0,0 +1,75 @@
+package socketio09
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// greeter answers a welcome with reply
+func greeter(client *SocketIOClient, reply string) {
+	client.On("welcome", func(c *SocketIOConnection, args []string) {
+		c.Emit("thanks", reply+" "+args[0])
+	})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	clientEnd, server := NewPipe()
+	var recording bytes.Buffer
+	recorder := NewRecordingConn(clientEnd, &recording)
+	client := NewClient(recorder, NewConnection())
+	greeter(client, "hello")
+
+	server.WriteMsg(`5:::{"name":"welcome","args":["ann"]}`)
+	if frame, _ := server.GetNextMsg(); frame != `5:::{"name":"thanks","args":["hello ann"]}` {
+		t.Fatalf("client wrote %q", frame)
+	}
+	client.Close()
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) < 2 || frames[0].Direction != Inbound || frames[1].Direction != Outbound {
+		t.Fatalf("unexpected recording %+v", frames)
+	}
+
+	replay := NewReplayConn(frames, 0)
+	replayed := NewClient(replay, NewConnection())
+	greeter(replayed, "hello")
+	if err := replay.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	replayed.Close()
+
+	replay = NewReplayConn(frames, 0)
+	diverging := NewClient(replay, NewConnection())
+	greeter(diverging, "bye")
+	if err := replay.Wait(time.Second); !errors.Is(err, ErrorReplayMismatch) {
+		t.Fatalf("divergence not reported: %v", err)
+	}
+	diverging.Close()
+}
+
+func TestReplayTiming(t *testing.T) {
+	start := time.Now()
+	frames := []RecordedFrame{
+		{Time: start, Direction: Inbound, Frame: "3:::a"},
+		{Time: start.Add(200 * time.Millisecond), Direction: Inbound, Frame: "3:::b"},
+	}
+	replay := NewReplayConn(frames, 4)
+	defer replay.Close()
+
+	began := time.Now()
+	replay.GetNextMsg()
+	if frame, _ := replay.GetNextMsg(); frame != "3:::b" {
+		t.Fatalf("got %q", frame)
+	}
+	if elapsed := time.Since(began); elapsed < 40*time.Millisecond || elapsed > 150*time.Millisecond {
+		t.Fatalf("second frame after %s, want about 50ms", elapsed)
+	}
+}
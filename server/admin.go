@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+SocketInfo describes a connected session, for introspection.
+*/
+type SocketInfo struct {
+	ID          string    `json:"sid"`
+	Transport   string    `json:"transport"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	// Namespaces are the endpoints the session is connected to, "" being the default namespace
+	Namespaces []string `json:"namespaces"`
+	// Rooms are the rooms joined in each namespace
+	Rooms map[string][]string `json:"rooms"`
+	// QueueDepth is the number of frames waiting to be sent
+	QueueDepth int `json:"queueDepth"`
+	// PendingAcks is the number of EmitWithAck calls waiting for the client
+	PendingAcks int `json:"pendingAcks"`
+}
+
+/*
+Inspect describes every session connected to this process, ordered by session id.
+*/
+func (s *Server) Inspect() []SocketInfo {
+	s.sessionsLock.RLock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessionsLock.RUnlock()
+
+	infos := make([]SocketInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		if info, connected := sess.info(); connected {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+func (sess *session) info() (SocketInfo, bool) {
+	sess.closeLock.Lock()
+	info := SocketInfo{
+		ID:          sess.id,
+		Transport:   sess.transport,
+		RemoteAddr:  sess.handshake.Address,
+		ConnectedAt: sess.connectedAt,
+		QueueDepth:  len(sess.outbound),
+		Rooms:       make(map[string][]string),
+	}
+	connected := !sess.closed && sess.transport != ""
+	sess.closeLock.Unlock()
+
+	sess.socketsLock.RLock()
+	for endpoint, so := range sess.sockets {
+		info.Namespaces = append(info.Namespaces, endpoint)
+		info.PendingAcks += so.acks.Pending()
+		if rooms := so.Rooms(); len(rooms) > 0 {
+			sort.Strings(rooms)
+			info.Rooms[endpoint] = rooms
+		}
+	}
+	sess.socketsLock.RUnlock()
+	sort.Strings(info.Namespaces)
+	return info, connected
+}
+
+/*
+adminBroadcast is the body of a test broadcast
+*/
+type adminBroadcast struct {
+	Namespace string          `json:"namespace"`
+	Room      string          `json:"room"`
+	Event     string          `json:"event"`
+	Args      json.RawMessage `json:"args"`
+}
+
+/*
+AdminHandler returns an http.Handler for operating the server, meant to be mounted away from
+the socket.io resource with http.StripPrefix. Every request must pass authorize, and is
+answered 401 Unauthorized otherwise; a nil authorize refuses everything.
+
+	GET  /sockets             lists the connected sockets as JSON, see SocketInfo
+	POST /sockets/[sid]/kick  disconnects a socket, answering 202 Accepted when it is held by
+	                          another process sharing the Store and PubSub
+	POST /broadcast           emits a test event, from a body like
+	                          {"namespace": "/chat", "room": "lobby", "event": "test", "args": {"text": "hi"}}
+	                          where args is the value given to Emit, and may be left out
+
+	http.Handle("/admin/", http.StripPrefix("/admin", srv.AdminHandler(func(r *http.Request) bool {
+		user, pass, ok := r.BasicAuth()
+		return ok && user == "admin" && pass == adminPassword
+	})))
+*/
+func (s *Server) AdminHandler(authorize func(*http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize == nil || !authorize(r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case len(parts) == 1 && parts[0] == "sockets" && r.Method == "GET":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s.Inspect())
+		case len(parts) == 3 && parts[0] == "sockets" && parts[2] == "kick" && r.Method == "POST":
+			s.serveAdminKick(w, r, parts[1])
+		case len(parts) == 1 && parts[0] == "broadcast" && r.Method == "POST":
+			s.serveAdminBroadcast(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+/*
+serveAdminKick disconnects a session held by this process, answering 204 No Content, or asks the
+other processes to through PubSub, answering 202 Accepted
+*/
+func (s *Server) serveAdminKick(w http.ResponseWriter, r *http.Request, sid string) {
+	if sess, exists := s.session(sid); exists {
+		sess.close(ReasonBooted, true)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if s.PubSub == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, exists, err := s.Store.Session(sid); err != nil || !exists {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.publish(dispatch{Kick: sid}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) serveAdminBroadcast(w http.ResponseWriter, r *http.Request) {
+	var b adminBroadcast
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ns, exists := s.namespace(b.Namespace)
+	if !exists {
+		http.Error(w, "namespace not found", http.StatusNotFound)
+		return
+	}
+	var args interface{}
+	if len(b.Args) != 0 {
+		args = b.Args
+	}
+	if err := ns.To(b.Room).Emit(b.Event, args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
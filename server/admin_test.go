@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	srv := NewServer()
+	joined := make(chan struct{}, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.Join("lobby")
+		joined <- struct{}{}
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	admin := httptest.NewServer(srv.AdminHandler(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	}))
+	defer admin.Close()
+
+	do := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, admin.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp, _ := http.Get(admin.URL + "/sockets")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unauthorized request answered %d", resp.StatusCode)
+	}
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	select {
+	case <-joined:
+	case <-time.After(time.Second):
+		t.Fatal("not connected")
+	}
+
+	resp = do("GET", "/sockets", "")
+	var infos []SocketInfo
+	json.NewDecoder(resp.Body).Decode(&infos)
+	resp.Body.Close()
+	if len(infos) != 1 {
+		t.Fatalf("got %d sockets", len(infos))
+	}
+	info := infos[0]
+	if info.Transport != TransportWebsocket || info.RemoteAddr == "" || info.ConnectedAt.IsZero() ||
+		len(info.Namespaces) != 1 || info.Rooms[""][0] != "lobby" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	resp = do("POST", "/broadcast", `{"room":"lobby","event":"test","args":"hi"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("broadcast answered %d", resp.StatusCode)
+	}
+	expectFrame(t, ws, `5:::{"name":"test","args":["hi"]}`)
+	resp = do("POST", "/broadcast", `{"room":"lobby","event":"ping"}`)
+	resp.Body.Close()
+	expectFrame(t, ws, `5:::{"name":"ping","args":[]}`)
+
+	resp = do("POST", "/sockets/"+info.ID+"/kick", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("kick answered %d", resp.StatusCode)
+	}
+	expectFrame(t, ws, "0::")
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("connection not closed after kick")
+	}
+	if n := len(srv.Inspect()); n != 0 {
+		t.Fatalf("%d sockets left after kick", n)
+	}
+}
+
+func TestAdminKickAcrossProcesses(t *testing.T) {
+	store, broker := NewMemoryStore(), NewMemoryBroker()
+	var srvs []*Server
+	for i := 0; i < 2; i++ {
+		srv := NewServer()
+		srv.Store = store
+		srv.PubSub = broker
+		srvs = append(srvs, srv)
+	}
+	ts := httptest.NewServer(srvs[1])
+	defer ts.Close()
+	admin := httptest.NewServer(srvs[0].AdminHandler(func(*http.Request) bool { return true }))
+	defer admin.Close()
+	kick := func(sid string) int {
+		resp, err := http.Post(admin.URL+"/sockets/"+sid+"/kick", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	sid := srvs[1].Inspect()[0].ID
+
+	if status := kick("unknown"); status != http.StatusNotFound {
+		t.Fatalf("kick of an unknown session answered %d", status)
+	}
+	if status := kick(sid); status != http.StatusAccepted {
+		t.Fatalf("kick of a remote session answered %d", status)
+	}
+	expectFrame(t, ws, "0::")
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+
+	"github.com/ruffrey/go-socketio09"
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+/*
+Broadcaster emits events to many sockets at once: every socket of an endpoint, or the members
+of one room, optionally leaving out the socket which is broadcasting.
+
+	srv.To("lobby").Emit("news", news)
+	so.Broadcast().To("lobby").Emit("joined", so.ID())
+*/
+type Broadcaster struct {
+	server *Server
+	key    roomKey
+	except *Socket
+}
+
+/*
+To narrows the broadcast to the members of room.
+*/
+func (b *Broadcaster) To(room string) *Broadcaster {
+	narrowed := *b
+	narrowed.key.room = room
+	return &narrowed
+}
+
+/*
+Clients lists the sockets the broadcast would reach.
+*/
+func (b *Broadcaster) Clients() []*Socket {
+	sockets := b.server.rooms.sockets(b.key)
+	clients := sockets[:0]
+	for _, so := range sockets {
+		if so != b.except {
+			clients = append(clients, so)
+		}
+	}
+	return clients
+}
+
+/*
+ClientIDs lists the session ids of the sockets the broadcast would reach, in every process
+sharing the server's Store.
+*/
+func (b *Broadcaster) ClientIDs() ([]string, error) {
+	sids, err := b.server.Store.Members(b.key.storeName())
+	if err != nil || b.except == nil {
+		return sids, err
+	}
+	ids := sids[:0]
+	for _, sid := range sids {
+		if sid != b.except.ID() {
+			ids = append(ids, sid)
+		}
+	}
+	return ids, nil
+}
+
+/*
+Emit sends the event to every socket of the broadcast, and publishes it to the other processes
+when the server has a PubSub. The frame is only encoded once. Sockets which cannot take the
+frame, because they are disconnecting or flooded, are skipped.
+*/
+func (b *Broadcaster) Emit(event string, args interface{}) error {
+	msg := &socketio09.Message{
+		Type:      spec.Event,
+		EventName: event,
+		Endpoint:  b.key.endpoint,
+	}
+	frame, err := socketio09.EncodeFrame(context.Background(), msg, args, b.server.codec(), nil)
+	if err != nil {
+		return err
+	}
+
+	except := ""
+	if b.except != nil {
+		except = b.except.ID()
+	}
+	b.server.deliver(b.key, except, frame)
+	return b.server.publish(dispatch{
+		Endpoint: b.key.endpoint,
+		Room:     b.key.room,
+		Except:   except,
+		Frame:    frame,
+	})
+}
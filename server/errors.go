@@ -0,0 +1,13 @@
+package server
+
+import "errors"
+
+var (
+	// ErrorSocketDisconnected indicates the socket was already disconnected
+	ErrorSocketDisconnected = errors.New("Socket is disconnected")
+	// ErrorTransportInUse indicates a session was used with a transport other than its own
+	ErrorTransportInUse = errors.New("Session is bound to another transport")
+	// ErrorServiceUnavailable is returned by an authorization function to refuse a handshake
+	// with 503 Service Unavailable
+	ErrorServiceUnavailable = errors.New("Service unavailable")
+)
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/ruffrey/go-socketio09"
+)
+
+/*
+handlers maps event names to their handler. The zero value is ready to use.
+*/
+type handlers struct {
+	callers     map[string]*socketio09.HandlerCaller
+	callersLock sync.RWMutex
+}
+
+func (h *handlers) on(event string, fn interface{}) error {
+	c, err := socketio09.NewHandlerCaller(fn)
+	if err != nil {
+		return err
+	}
+
+	h.callersLock.Lock()
+	defer h.callersLock.Unlock()
+	if h.callers == nil {
+		h.callers = make(map[string]*socketio09.HandlerCaller)
+	}
+	h.callers[event] = c
+	return nil
+}
+
+func (h *handlers) find(event string) (fn *socketio09.HandlerCaller, exists bool) {
+	h.callersLock.RLock()
+	defer h.callersLock.RUnlock()
+	fn, exists = h.callers[event]
+	return fn, exists
+}
+
+/*
+fire calls the handler for an event raised by the server itself, such as "disconnect". arg is
+converted to the handler's argument type through the codec, and the handler gets the zero value
+if that is not possible.
+*/
+func (h *handlers) fire(codec socketio09.Codec, receiver interface{}, event string, arg interface{}) {
+	fn, exists := h.find(event)
+	if !exists {
+		return
+	}
+
+	args := fn.NewArgs()
+	if args != nil && arg != nil {
+		if data, err := codec.Marshal(arg); err == nil {
+			codec.Unmarshal(data, args)
+		}
+	}
+	fn.Call(receiver, args)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/*
+HandshakeData describes the handshake request of a session. It is given to authorization
+functions, and every socket of the session can read it with Handshake.
+*/
+type HandshakeData struct {
+	// Headers are the handshake request headers
+	Headers http.Header
+	// Query is the handshake query string. For a namespace, it also holds the query of the
+	// connect packet.
+	Query url.Values
+	// Address is the remote address of the client, without the port
+	Address string
+	// Secure is true when the handshake was made over TLS
+	Secure bool
+	// Time is when the handshake was made
+	Time time.Time
+
+	// values are shared by every copy of the handshake data of a session
+	values *handshakeValues
+}
+
+type handshakeValues struct {
+	m    map[string]interface{}
+	lock sync.RWMutex
+}
+
+/*
+Cookies parses the cookies sent with the handshake.
+*/
+func (hd *HandshakeData) Cookies() []*http.Cookie {
+	return (&http.Request{Header: hd.Headers}).Cookies()
+}
+
+/*
+Cookie returns the named handshake cookie, or http.ErrNoCookie.
+*/
+func (hd *HandshakeData) Cookie(name string) (*http.Cookie, error) {
+	return (&http.Request{Header: hd.Headers}).Cookie(name)
+}
+
+// handshakeValuesLock guards making the values of handshake data built without them
+var handshakeValuesLock sync.Mutex
+
+/*
+shared returns the values of the session, making them for handshake data built as a literal
+*/
+func (hd *HandshakeData) shared() *handshakeValues {
+	handshakeValuesLock.Lock()
+	defer handshakeValuesLock.Unlock()
+	if hd.values == nil {
+		hd.values = &handshakeValues{m: make(map[string]interface{})}
+	}
+	return hd.values
+}
+
+/*
+Set attaches a value to the session, typically from the authorization function. Every socket of
+the session can read it from its Handshake.
+*/
+func (hd *HandshakeData) Set(key string, value interface{}) {
+	values := hd.shared()
+	values.lock.Lock()
+	values.m[key] = value
+	values.lock.Unlock()
+}
+
+/*
+Get returns a value attached to the session with Set.
+*/
+func (hd *HandshakeData) Get(key string) (value interface{}, exists bool) {
+	values := hd.shared()
+	values.lock.RLock()
+	defer values.lock.RUnlock()
+	value, exists = values.m[key]
+	return value, exists
+}
+
+/*
+Values copies the values attached to the session with Set, for a Store which keeps sessions out
+of the process to encode along with the exported fields.
+*/
+func (hd *HandshakeData) Values() map[string]interface{} {
+	values := hd.shared()
+	values.lock.RLock()
+	defer values.lock.RUnlock()
+	copied := make(map[string]interface{}, len(values.m))
+	for k, v := range values.m {
+		copied[k] = v
+	}
+	return copied
+}
+
+/*
+NewHandshakeData builds the handshake data of a session from its encoded form, as a Store
+keeping sessions out of the process decodes it. values are the ones returned by Values, and may
+be nil.
+*/
+func NewHandshakeData(headers http.Header, query url.Values, address string, secure bool,
+	handshaken time.Time, values map[string]interface{}) *HandshakeData {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		m[k] = v
+	}
+	return &HandshakeData{
+		Headers: headers,
+		Query:   query,
+		Address: address,
+		Secure:  secure,
+		Time:    handshaken,
+		values:  &handshakeValues{m: m},
+	}
+}
+
+func newHandshakeData(r *http.Request) *HandshakeData {
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		address = r.RemoteAddr
+	}
+	return NewHandshakeData(r.Header, r.URL.Query(), address, r.TLS != nil, time.Now(), nil)
+}
+
+/*
+withQuery copies the handshake data, adding the values of query to the handshake query
+*/
+func (hd *HandshakeData) withQuery(query url.Values) *HandshakeData {
+	hd.shared()
+	copied := *hd
+	copied.Query = url.Values{}
+	for k, v := range hd.Query {
+		copied.Query[k] = v
+	}
+	for k, v := range query {
+		copied.Query[k] = v
+	}
+	return &copied
+}
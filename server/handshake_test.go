@@ -0,0 +1,93 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHandshakeAuthorizationStatus(t *testing.T) {
+	srv := NewServer()
+	srv.Authorization(func(hd *HandshakeData) (bool, error) {
+		switch hd.Query.Get("case") {
+		case "busy":
+			return false, ErrorServiceUnavailable
+		case "broken":
+			return false, errors.New("broken")
+		case "denied":
+			return false, nil
+		}
+		return true, nil
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for query, want := range map[string]int{
+		"ok":     http.StatusOK,
+		"denied": http.StatusUnauthorized,
+		"busy":   http.StatusServiceUnavailable,
+		"broken": http.StatusInternalServerError,
+	} {
+		resp, err := http.Get(ts.URL + "/socket.io/1/?case=" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("case %s answered %d, want %d", query, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestHandshakeDataReachesSockets(t *testing.T) {
+	srv := NewServer()
+	srv.Authorization(func(hd *HandshakeData) (bool, error) {
+		cookie, err := hd.Cookie("user")
+		if err != nil {
+			return false, nil
+		}
+		hd.Set("user", cookie.Value)
+		return true, nil
+	})
+	users := make(chan interface{}, 2)
+	srv.On(OnConnection, func(so *Socket) {
+		user, _ := so.Handshake().Get("user")
+		users <- user
+	})
+	srv.Of("/chat").On(OnConnection, func(so *Socket) {
+		user, _ := so.Handshake().Get("user")
+		users <- user
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/socket.io/1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("handshake without cookie answered %d", resp.StatusCode)
+	}
+
+	ws := dialRawWithHeader(t, ts, http.Header{"Cookie": {"user=ada"}})
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte("1::/chat"))
+	expectFrame(t, ws, "1::/chat")
+
+	for i := 0; i < 2; i++ {
+		select {
+		case user := <-users:
+			if user != "ada" {
+				t.Fatalf("socket saw user %v", user)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("connection handler not called")
+		}
+	}
+}
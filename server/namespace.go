@@ -0,0 +1,101 @@
+package server
+
+import "sync"
+
+/*
+Namespace is a socket.io endpoint, such as "/chat", which clients connect to with a `1::/chat`
+packet over their existing session. Each namespace has its own "connection" handlers, rooms and
+authorization. The default namespace is named "".
+
+	chat := srv.Of("/chat").Authorization(func(hd *server.HandshakeData) (bool, error) {
+		return hd.Query.Get("token") == secret, nil
+	})
+	chat.On("connection", func(so *server.Socket) {})
+*/
+type Namespace struct {
+	server *Server
+	name   string
+
+	handlers handlers
+
+	authorize     func(*HandshakeData) (bool, error)
+	authorizeLock sync.RWMutex
+}
+
+/*
+Of returns the namespace named name, creating it the first time.
+*/
+func (s *Server) Of(name string) *Namespace {
+	s.namespacesLock.Lock()
+	defer s.namespacesLock.Unlock()
+
+	if ns, exists := s.namespaces[name]; exists {
+		return ns
+	}
+	ns := &Namespace{server: s, name: name}
+	s.namespaces[name] = ns
+	return ns
+}
+
+func (s *Server) namespace(name string) (*Namespace, bool) {
+	s.namespacesLock.RLock()
+	defer s.namespacesLock.RUnlock()
+	ns, exists := s.namespaces[name]
+	return ns, exists
+}
+
+// Name is the endpoint of the namespace
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+/*
+Authorization sets a function which decides whether a client may connect to the namespace. It
+gets the session's handshake data, with the query of the connect packet added. Returning false
+sends the client an `unauthorized` error packet, and returning an error sends the error instead.
+*/
+func (ns *Namespace) Authorization(fn func(*HandshakeData) (bool, error)) *Namespace {
+	ns.authorizeLock.Lock()
+	ns.authorize = fn
+	ns.authorizeLock.Unlock()
+	return ns
+}
+
+/*
+On adds a namespace level handler. The only namespace event is "connection", fired with each
+*Socket which connects to the namespace.
+*/
+func (ns *Namespace) On(event string, fn interface{}) error {
+	return ns.handlers.on(event, fn)
+}
+
+/*
+Sockets broadcasts to every socket connected to the namespace.
+*/
+func (ns *Namespace) Sockets() *Broadcaster {
+	return &Broadcaster{
+		server: ns.server,
+		key:    roomKey{endpoint: ns.name},
+	}
+}
+
+/*
+To broadcasts to the sockets of the namespace which joined room.
+*/
+func (ns *Namespace) To(room string) *Broadcaster {
+	return ns.Sockets().To(room)
+}
+
+/*
+authorized runs the authorization function, if there is one.
+*/
+func (ns *Namespace) authorized(hd *HandshakeData) (bool, error) {
+	ns.authorizeLock.RLock()
+	authorize := ns.authorize
+	ns.authorizeLock.RUnlock()
+
+	if authorize == nil {
+		return true, nil
+	}
+	return authorize(hd)
+}
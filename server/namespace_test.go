@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNamespaceConnectAndRouting(t *testing.T) {
+	srv := NewServer()
+	tokens := make(chan string, 2)
+	srv.Of("/chat").Authorization(func(hd *HandshakeData) (bool, error) {
+		tokens <- hd.Query.Get("token")
+		return hd.Query.Get("token") == "secret", nil
+	}).On(OnConnection, func(so *Socket) {
+		so.On("say", func(so *Socket, args []string) {
+			so.Emit("said", args[0])
+		})
+	})
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("say", func(so *Socket, args []string) {
+			so.Emit("default", args[0])
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+
+	ws.WriteMessage(websocket.TextMessage, []byte("1::/chat?token=wrong"))
+	expectFrame(t, ws, "7::/chat:unauthorized+")
+
+	ws.WriteMessage(websocket.TextMessage, []byte("1::/nope"))
+	expectFrame(t, ws, "7::/nope:invalid namespace+")
+
+	ws.WriteMessage(websocket.TextMessage, []byte("1::/chat?token=secret"))
+	expectFrame(t, ws, "1::/chat")
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`5::/chat:{"name":"say","args":["hi"]}`))
+	expectFrame(t, ws, `5::/chat:{"name":"said","args":["hi"]}`)
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:::{"name":"say","args":["hi"]}`))
+	expectFrame(t, ws, `5:::{"name":"default","args":["hi"]}`)
+
+	if <-tokens != "wrong" || <-tokens != "secret" {
+		t.Fatal("authorization did not get the connect packet query")
+	}
+}
+
+func TestNamespaceAuthorizationError(t *testing.T) {
+	srv := NewServer()
+	srv.Of("/admin").Authorization(func(hd *HandshakeData) (bool, error) {
+		return false, errors.New("overloaded")
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte("1::/admin"))
+	expectFrame(t, ws, "7::/admin:overloaded+")
+}
+
+func TestNamespaceDisconnectKeepsSession(t *testing.T) {
+	srv := NewServer()
+	reasons := make(chan string, 1)
+	srv.Of("/chat").On(OnConnection, func(so *Socket) {
+		so.Join("room")
+		so.On("disconnect", func(so *Socket, reason string) {
+			reasons <- reason
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte("1::/chat"))
+	expectFrame(t, ws, "1::/chat")
+	ws.WriteMessage(websocket.TextMessage, []byte("0::/chat"))
+
+	select {
+	case reason := <-reasons:
+		if reason != ReasonNamespaceDisconnect {
+			t.Fatalf("unexpected reason %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("namespace disconnect not fired")
+	}
+	if n := len(srv.Of("/chat").To("room").Clients()); n != 0 {
+		t.Fatalf("room still has %d clients", n)
+	}
+
+	srv.Sockets().Emit("still", "here")
+	expectFrame(t, ws, `5:::{"name":"still","args":["here"]}`)
+}
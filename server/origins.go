@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// AnyOrigin allows requests from every origin
+const AnyOrigin = "*:*"
+
+/*
+originAllowed checks the Origin header of a request, or its Referer when there is none, against
+the Origins allow-list. Entries are `host:port`, and either side can be `*`, as in Node.js 0.9.
+The reason is set when the origin is refused.
+*/
+func (s *Server) originAllowed(r *http.Request) (ok bool, reason string) {
+	for _, allowed := range s.Origins {
+		if allowed == AnyOrigin {
+			return true, ""
+		}
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	if origin == "" {
+		return false, "origin missing from handshake, yet required by config"
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Hostname() == "" {
+		return false, "error parsing origin " + origin
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	for _, allowed := range s.Origins {
+		switch allowed {
+		case u.Hostname() + ":" + port, u.Hostname() + ":*", "*:" + port:
+			return true, ""
+		}
+	}
+	return false, "illegal origin: " + origin
+}
+
+/*
+checkOrigin is the websocket upgrader's origin check. The upgrader answers 403 Forbidden when it
+fails.
+*/
+func (s *Server) checkOrigin(r *http.Request) bool {
+	ok, reason := s.originAllowed(r)
+	if !ok {
+		s.logger().Warn("websocket upgrade refused", "reason", reason, "remote", r.RemoteAddr)
+	}
+	return ok
+}
+
+/*
+allowCORS refuses requests from origins which are not allowed with 403 Forbidden, logging msg,
+and lets browsers on the allowed ones read the response, with cookies. It answers a preflight
+request itself, and returns false to say the request was answered.
+*/
+func (s *Server) allowCORS(w http.ResponseWriter, r *http.Request, msg string) bool {
+	if ok, reason := s.originAllowed(r); !ok {
+		s.logger().Warn(msg, "reason", reason, "remote", r.RemoteAddr)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	if r.Method != "OPTIONS" {
+		return true
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return false
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		origins []string
+		origin  string
+		ok      bool
+	}{
+		{[]string{AnyOrigin}, "", true},
+		{[]string{"example.com:80"}, "http://example.com", true},
+		{[]string{"example.com:80"}, "https://example.com", false},
+		{[]string{"example.com:443"}, "https://example.com", true},
+		{[]string{"example.com:*"}, "http://example.com:8080", true},
+		{[]string{"*:8080"}, "http://other.org:8080", true},
+		{[]string{"example.com:*"}, "http://evil.com", false},
+		{[]string{"example.com:*"}, "", false},
+		{[]string{"example.com:*"}, "null", false},
+	}
+	for _, test := range tests {
+		srv := NewServer()
+		srv.Origins = test.origins
+		r := httptest.NewRequest("GET", "/socket.io/1/", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if ok, reason := srv.originAllowed(r); ok != test.ok {
+			t.Errorf("origins %v, origin %q: got %v (%s)", test.origins, test.origin, ok, reason)
+		}
+	}
+}
+
+func TestHandshakeOrigins(t *testing.T) {
+	srv := NewServer()
+	srv.Origins = []string{"app.example.com:*"}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	handshake := func(method, origin string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+"/socket.io/1/", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := handshake("GET", "http://evil.com"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("illegal origin answered %d", resp.StatusCode)
+	}
+	resp := handshake("GET", "http://app.example.com")
+	if resp.StatusCode != http.StatusOK ||
+		resp.Header.Get("Access-Control-Allow-Origin") != "http://app.example.com" ||
+		resp.Header.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("unexpected answer %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := handshake("OPTIONS", "http://app.example.com"); resp.StatusCode != http.StatusNoContent ||
+		resp.Header.Get("Access-Control-Allow-Methods") == "" {
+		t.Fatalf("unexpected preflight answer %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestPollingCORS(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/socket.io/1/xhr-polling/"+handshakeSID(t, ts), nil)
+	req.Header.Set("Origin", "http://app.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Access-Control-Allow-Origin") != "http://app.example.com" ||
+		resp.Header.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("missing CORS headers %v", resp.Header)
+	}
+}
+
+func TestPollingOrigin(t *testing.T) {
+	srv := NewServer()
+	srv.Origins = []string{"app.example.com:*"}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/socket.io/1/", nil)
+	req.Header.Set("Origin", "http://app.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	sid := strings.Split(string(body), ":")[0]
+
+	for _, method := range []string{"GET", "POST", "OPTIONS"} {
+		req, _ := http.NewRequest(method, ts.URL+"/socket.io/1/xhr-polling/"+sid, strings.NewReader("2::"))
+		req.Header.Set("Origin", "http://evil.com")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" ||
+			resp.Header.Get("Access-Control-Allow-Credentials") != "" {
+			t.Fatalf("%s from an illegal origin answered %d %v", method, resp.StatusCode, resp.Header)
+		}
+	}
+}
+
+func TestWebsocketOrigin(t *testing.T) {
+	srv := NewServer()
+	srv.Origins = []string{"app.example.com:*"}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/socket.io/1/", nil)
+	req.Header.Set("Referer", "http://app.example.com/chat")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	sid := strings.Split(string(body), ":")[0]
+
+	_, resp, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/socket.io/1/websocket/"+sid,
+		http.Header{"Origin": {"http://evil.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("upgrade from an illegal origin was not refused: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+const (
+	// TransportXHRPolling is the xhr-polling transport id
+	TransportXHRPolling = "xhr-polling"
+	// TransportJSONPPolling is the jsonp-polling transport id
+	TransportJSONPPolling = "jsonp-polling"
+)
+
+/*
+servePolling handles both polling transports. A GET waits for queued frames, up to the polling
+duration, and answers with all of them using the multi-message framing. A POST delivers frames
+from the client. When no GET is waiting, the session is closed after the close timeout. Browsers
+on the allowed origins may use both, with credentials.
+*/
+func (s *Server) servePolling(w http.ResponseWriter, r *http.Request, transport, sid string) {
+	if !s.allowCORS(w, r, "polling refused") {
+		return
+	}
+	sess, exists := s.handshakenSession(sid)
+	if !exists {
+		writePollingPayload(w, r, transport, errorFrame("", ReasonNotHandshaken, AdviceReconnect))
+		return
+	}
+	first, err := sess.attach(transport, nil)
+	if err != nil {
+		writePollingPayload(w, r, transport, attachErrorFrame(err))
+		return
+	}
+	if first {
+		sess.open()
+	}
+
+	switch r.Method {
+	case "GET":
+		frames := sess.poll(r.Context().Done())
+		if len(frames) == 0 {
+			// the client went away, and will get the frames on its next poll
+			return
+		}
+		writePollingPayload(w, r, transport, socketio09.EncodePayload(frames))
+	case "POST":
+		s.ingestPolling(w, r, transport, sess)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+/*
+ingestPolling handles the frames a polling client POSTs. jsonp-polling posts them as the `d`
+field of a form, with newlines escaped once more.
+*/
+func (s *Server) ingestPolling(w http.ResponseWriter, r *http.Request, transport string, sess *session) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data := string(body)
+	if transport == TransportJSONPPolling {
+		form, err := url.ParseQuery(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data = jsonpNewlines.Replace(form.Get("d"))
+	}
+
+	frames, err := socketio09.DecodePayload(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, frame := range frames {
+		if !sess.receive(frame) {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	w.Write([]byte("1"))
+}
+
+/*
+jsonpNewlines undoes the newline escaping of the 0.9 jsonp-polling client, which sends a newline
+as `\n` and an escaped newline within a JSON string as `\\n`. As in the Node.js server, `\n` is
+turned back into a newline except where it follows a backslash, and `\\n` into `\n`.
+*/
+var jsonpNewlines = strings.NewReplacer(`\\n`, `\n`, `\n`, "\n")
+
+/*
+writePollingPayload answers a GET. jsonp-polling wraps the payload in a call to the client's
+`io.j[i]` callback, where i comes from the query.
+*/
+func writePollingPayload(w http.ResponseWriter, r *http.Request, transport, payload string) {
+	if transport != TransportJSONPPolling {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.Write([]byte(payload))
+		return
+	}
+
+	index := r.URL.Query().Get("i")
+	if index == "" || strings.Trim(index, "0123456789") != "" {
+		index = "0"
+	}
+	quoted, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "text/javascript; charset=UTF-8")
+	w.Write([]byte("io.j[" + index + "](" + string(quoted) + ");"))
+}
+
+/*
+poll waits for queued frames and takes all of them. It answers a noop when nothing arrives
+within the polling duration, and returns nil if cancel is closed first. The close timeout only
+runs while no poll is waiting.
+*/
+func (sess *session) poll(cancel <-chan struct{}) []string {
+	sess.pollLock.Lock()
+	defer sess.pollLock.Unlock()
+
+	sess.stopCloseTimer()
+	defer sess.startCloseTimer()
+
+	timeout := time.NewTimer(sess.server.PollingDuration)
+	defer timeout.Stop()
+
+	select {
+	case frame := <-sess.outbound:
+		return sess.drain([]string{frame})
+	case <-timeout.C:
+		return []string{spec.Noop + "::"}
+	case <-sess.done:
+		frames := sess.drain(nil)
+		if sess.notifyClient {
+			frames = append(frames, spec.Disconnect+"::")
+		}
+		return frames
+	case <-cancel:
+		return nil
+	}
+}
+
+// drain appends every frame already queued to frames
+func (sess *session) drain(frames []string) []string {
+	for {
+		select {
+		case frame := <-sess.outbound:
+			frames = append(frames, frame)
+		default:
+			return frames
+		}
+	}
+}
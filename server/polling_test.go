@@ -0,0 +1,213 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+)
+
+func handshakeSID(t *testing.T, ts *httptest.Server) string {
+	resp, err := http.Get(ts.URL + "/socket.io/1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return strings.Split(string(body), ":")[0]
+}
+
+func pollOnce(t *testing.T, pollURL string) (string, string) {
+	resp, err := http.Get(pollURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return string(body), resp.Header.Get("Content-Type")
+}
+
+func postFrames(t *testing.T, pollURL, contentType, body string) {
+	resp, err := http.Post(pollURL, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(reply) != "1" {
+		t.Fatalf("POST answered %q", reply)
+	}
+}
+
+func TestXHRPolling(t *testing.T) {
+	srv := NewServer()
+	srv.PollingDuration = 50 * time.Millisecond
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("echo", func(so *Socket, args []string) []string {
+			return args
+		})
+		so.Emit("welcome", "hi")
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	pollURL := ts.URL + "/socket.io/1/xhr-polling/" + handshakeSID(t, ts)
+	body, _ := pollOnce(t, pollURL)
+	if body != socketio09.EncodePayload([]string{"1::", `5:::{"name":"welcome","args":["hi"]}`}) {
+		t.Fatalf("first poll got %q", body)
+	}
+
+	if body, _ = pollOnce(t, pollURL); body != "8::" {
+		t.Fatalf("idle poll got %q, want a noop", body)
+	}
+
+	postFrames(t, pollURL, "text/plain", socketio09.EncodePayload([]string{
+		`5:1+::{"name":"echo","args":["a"]}`,
+		`5:2+::{"name":"echo","args":["b"]}`,
+	}))
+
+	var frames []string
+	for len(frames) < 2 {
+		body, _ = pollOnce(t, pollURL)
+		got, err := socketio09.DecodePayload(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, got...)
+	}
+	if frames[0] != `6:::1+[["a"]]` && frames[1] != `6:::1+[["a"]]` {
+		t.Fatalf("acks not delivered: %q", frames)
+	}
+}
+
+func TestJSONPPolling(t *testing.T) {
+	srv := NewServer()
+	received := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("say", func(so *Socket, args []string) {
+			received <- args[0]
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	pollURL := ts.URL + "/socket.io/1/jsonp-polling/" + handshakeSID(t, ts)
+	body, contentType := pollOnce(t, pollURL+"?i=3")
+	if body != `io.j[3]("1::");` || !strings.HasPrefix(contentType, "text/javascript") {
+		t.Fatalf("jsonp poll got %q (%s)", body, contentType)
+	}
+
+	form := url.Values{"d": {`5:::{"name":"say","args":["line\\nbreak"]}`}}
+	postFrames(t, pollURL, "application/x-www-form-urlencoded", form.Encode())
+	select {
+	case text := <-received:
+		if text != "line\nbreak" {
+			t.Fatalf("received %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("jsonp POST not handled")
+	}
+}
+
+func TestPollingCloseTimeout(t *testing.T) {
+	srv := NewServer()
+	srv.CloseTimeout = 50 * time.Millisecond
+	reasons := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("disconnect", func(so *Socket, reason string) {
+			reasons <- reason
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sid := handshakeSID(t, ts)
+	pollOnce(t, ts.URL+"/socket.io/1/xhr-polling/"+sid)
+
+	select {
+	case reason := <-reasons:
+		if reason != ReasonCloseTimeout {
+			t.Fatalf("unexpected reason %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed")
+	}
+	if _, exists := srv.session(sid); exists {
+		t.Fatal("session still registered")
+	}
+
+	body, _ := pollOnce(t, ts.URL+"/socket.io/1/xhr-polling/"+sid)
+	if body != "7:::client not handshaken+reconnect" {
+		t.Fatalf("poll after close got %q", body)
+	}
+}
+
+func TestPollDuringDisconnect(t *testing.T) {
+	srv := NewServer()
+	srv.CloseTimeout = 50 * time.Millisecond
+	closing := make(chan struct{})
+	release := make(chan struct{})
+	connections := make(chan struct{}, 2)
+	srv.On(OnConnection, func(so *Socket) {
+		connections <- struct{}{}
+		so.On("disconnect", func(so *Socket, reason string) {
+			close(closing)
+			<-release
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sid := handshakeSID(t, ts)
+	pollURL := ts.URL + "/socket.io/1/xhr-polling/" + sid
+	pollOnce(t, pollURL)
+	<-connections
+
+	select {
+	case <-closing:
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed")
+	}
+	body, _ := pollOnce(t, pollURL)
+	close(release)
+	if body != "7:::client not handshaken+reconnect" {
+		t.Fatalf("poll while the disconnect handler ran got %q", body)
+	}
+	select {
+	case <-connections:
+		t.Fatal("closed session connected again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestJSONPPollingNewlines(t *testing.T) {
+	srv := NewServer()
+	received := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("say", func(so *Socket, args []string) {
+			received <- args[0]
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	pollURL := ts.URL + "/socket.io/1/jsonp-polling/" + handshakeSID(t, ts)
+	pollOnce(t, pollURL)
+
+	// the newline between the fields is escaped as \n, the one within the text as \\n
+	form := url.Values{"d": {`5:::{"name":"say",\n"args":["two\\nlines"]}`}}
+	postFrames(t, pollURL, "application/x-www-form-urlencoded", form.Encode())
+	select {
+	case text := <-received:
+		if text != "two\nlines" {
+			t.Fatalf("received %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("jsonp POST with newlines not handled")
+	}
+}
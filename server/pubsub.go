@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// dispatchChannel is the PubSub channel broadcasts are published on
+const dispatchChannel = "dispatch"
+
+/*
+PubSub connects server processes which share a Store, so that a broadcast made in one process
+also reaches the sockets connected to the others. An adapter for a message broker, such as
+Redis, implements it.
+
+Publish must deliver the message to every subscriber of the channel in every process, including
+the publishing process itself; the server ignores its own messages. Handlers may be called from
+any goroutine, and must be called in publishing order for a given publisher.
+*/
+type PubSub interface {
+	Publish(channel string, message []byte) error
+	Subscribe(channel string, handler func(message []byte)) error
+}
+
+/*
+MemoryBroker is a PubSub within one process. It connects several servers running in the same
+process, which is mostly useful for tests.
+*/
+type MemoryBroker struct {
+	subscribers map[string][]func(message []byte)
+	lock        sync.RWMutex
+}
+
+// NewMemoryBroker returns a broker without subscribers
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string][]func(message []byte))}
+}
+
+// Publish calls every handler subscribed to channel, synchronously
+func (mb *MemoryBroker) Publish(channel string, message []byte) error {
+	mb.lock.RLock()
+	handlers := mb.subscribers[channel]
+	mb.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+// Subscribe adds a handler for channel
+func (mb *MemoryBroker) Subscribe(channel string, handler func(message []byte)) error {
+	mb.lock.Lock()
+	mb.subscribers[channel] = append(mb.subscribers[channel], handler)
+	mb.lock.Unlock()
+	return nil
+}
+
+/*
+dispatch is published to the other processes: either a broadcast, with its frame already
+encoded, a session to Kick, or a session Taken over by the publishing process.
+*/
+type dispatch struct {
+	Node     string `json:"node"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Room     string `json:"room,omitempty"`
+	Except   string `json:"except,omitempty"`
+	Frame    string `json:"frame,omitempty"`
+	Kick     string `json:"kick,omitempty"`
+	Taken    string `json:"taken,omitempty"`
+}
+
+/*
+subscribe starts listening for broadcasts from other processes, once.
+*/
+func (s *Server) subscribe() {
+	s.subscribeOnce.Do(func() {
+		if s.PubSub == nil {
+			return
+		}
+		s.PubSub.Subscribe(dispatchChannel, s.onDispatch)
+	})
+}
+
+func (s *Server) publish(d dispatch) error {
+	if s.PubSub == nil {
+		return nil
+	}
+	d.Node = s.node
+	message, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return s.PubSub.Publish(dispatchChannel, message)
+}
+
+func (s *Server) onDispatch(message []byte) {
+	var d dispatch
+	if err := json.Unmarshal(message, &d); err != nil || d.Node == s.node {
+		return
+	}
+	if d.Kick != "" {
+		if sess, exists := s.session(d.Kick); exists {
+			sess.close(ReasonBooted, true)
+		}
+		return
+	}
+	if d.Taken != "" {
+		if sess, exists := s.session(d.Taken); exists {
+			sess.discard()
+		}
+		return
+	}
+	s.deliver(roomKey{endpoint: d.Endpoint, room: d.Room}, d.Except, d.Frame)
+}
+
+/*
+deliver queues a frame for the local sockets in a room, except the socket with session id except
+*/
+func (s *Server) deliver(key roomKey, except string, frame string) {
+	for _, so := range s.rooms.sockets(key) {
+		if except != "" && so.ID() == except {
+			continue
+		}
+		so.session.send(frame)
+	}
+}
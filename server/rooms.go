@@ -0,0 +1,119 @@
+package server
+
+import "sync"
+
+/*
+roomKey scopes a room to an endpoint, since each namespace has its own rooms. The room named ""
+holds every socket connected to the endpoint.
+*/
+type roomKey struct {
+	endpoint string
+	room     string
+}
+
+// storeName is the room's name in the Store. Endpoints never contain ':'.
+func (key roomKey) storeName() string {
+	return key.endpoint + ":" + key.room
+}
+
+/*
+rooms tracks room membership both ways, so a socket can leave all of its rooms when it
+disconnects. Once it has, the socket can join no more rooms. The zero value is ready to use.
+*/
+type rooms struct {
+	members map[roomKey]map[*Socket]struct{}
+	joined  map[*Socket]map[roomKey]struct{}
+	lock    sync.RWMutex
+}
+
+// join adds so to the room, and returns false when so has already left all of its rooms
+func (r *rooms) join(key roomKey, so *Socket) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if so.left {
+		return false
+	}
+
+	if r.members == nil {
+		r.members = make(map[roomKey]map[*Socket]struct{})
+		r.joined = make(map[*Socket]map[roomKey]struct{})
+	}
+	if r.members[key] == nil {
+		r.members[key] = make(map[*Socket]struct{})
+	}
+	r.members[key][so] = struct{}{}
+	if r.joined[so] == nil {
+		r.joined[so] = make(map[roomKey]struct{})
+	}
+	r.joined[so][key] = struct{}{}
+	return true
+}
+
+func (r *rooms) leave(key roomKey, so *Socket) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.remove(key, so)
+}
+
+/*
+leaveAll removes so from every room, and returns the rooms it left. so cannot join rooms
+afterwards.
+*/
+func (r *rooms) leaveAll(so *Socket) []roomKey {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	so.left = true
+
+	keys := make([]roomKey, 0, len(r.joined[so]))
+	for key := range r.joined[so] {
+		keys = append(keys, key)
+		r.remove(key, so)
+	}
+	return keys
+}
+
+// remove must be called with the lock held
+func (r *rooms) remove(key roomKey, so *Socket) {
+	delete(r.members[key], so)
+	if len(r.members[key]) == 0 {
+		delete(r.members, key)
+	}
+	delete(r.joined[so], key)
+	if len(r.joined[so]) == 0 {
+		delete(r.joined, so)
+	}
+}
+
+// hasLeft tells whether leaveAll was called for so
+func (r *rooms) hasLeft(so *Socket) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return so.left
+}
+
+func (r *rooms) sockets(key roomKey) []*Socket {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	sockets := make([]*Socket, 0, len(r.members[key]))
+	for so := range r.members[key] {
+		sockets = append(sockets, so)
+	}
+	return sockets
+}
+
+// roomsOf lists the rooms so joined itself, leaving out the endpoint-wide room
+func (r *rooms) roomsOf(so *Socket) []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.joined[so]))
+	for key := range r.joined[so] {
+		if key.room != "" {
+			names = append(names, key.room)
+		}
+	}
+	return names
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRoomsBroadcast(t *testing.T) {
+	srv := NewServer()
+	joined := make(chan *Socket, 3)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("join", func(so *Socket, args []string) {
+			so.Join(args[0])
+			joined <- so
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var clients []*websocket.Conn
+	var sockets []*Socket
+	for _, room := range []string{"lobby", "lobby", "kitchen"} {
+		ws := dialRaw(t, ts)
+		defer ws.Close()
+		expectFrame(t, ws, "1::")
+		ws.WriteMessage(websocket.TextMessage, []byte(`5:::{"name":"join","args":["`+room+`"]}`))
+		select {
+		case so := <-joined:
+			sockets = append(sockets, so)
+		case <-time.After(time.Second):
+			t.Fatal("join not handled")
+		}
+		clients = append(clients, ws)
+	}
+
+	if rooms := sockets[0].Rooms(); len(rooms) != 1 || rooms[0] != "lobby" {
+		t.Fatalf("unexpected rooms %v", rooms)
+	}
+
+	srv.To("lobby").Emit("news", "lobby only")
+	expectFrame(t, clients[0], `5:::{"name":"news","args":["lobby only"]}`)
+	expectFrame(t, clients[1], `5:::{"name":"news","args":["lobby only"]}`)
+
+	sockets[0].Broadcast().Emit("news", "not the sender")
+	expectFrame(t, clients[1], `5:::{"name":"news","args":["not the sender"]}`)
+	expectFrame(t, clients[2], `5:::{"name":"news","args":["not the sender"]}`)
+
+	srv.Sockets().Emit("news", "everyone")
+	for _, ws := range clients {
+		expectFrame(t, ws, `5:::{"name":"news","args":["everyone"]}`)
+	}
+
+	sockets[1].Disconnect()
+	expectFrame(t, clients[1], "0::")
+	if n := len(srv.To("lobby").Clients()); n != 1 {
+		t.Fatalf("lobby has %d clients after disconnect, want 1", n)
+	}
+}
+
+func TestRoomsConcurrentJoins(t *testing.T) {
+	var r rooms
+	sockets := make([]*Socket, 50)
+	for i := range sockets {
+		sockets[i] = &Socket{}
+	}
+
+	var wg sync.WaitGroup
+	for _, so := range sockets {
+		wg.Add(1)
+		go func(so *Socket) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				key := roomKey{room: strconv.Itoa(j % 5)}
+				r.join(key, so)
+				r.sockets(key)
+				if j%2 == 0 {
+					r.leave(key, so)
+				}
+			}
+			r.leaveAll(so)
+		}(so)
+	}
+	wg.Wait()
+
+	if len(r.members) != 0 || len(r.joined) != 0 {
+		t.Fatalf("rooms not cleaned up: %d members, %d joined", len(r.members), len(r.joined))
+	}
+}
+
+func TestJoinAfterDisconnect(t *testing.T) {
+	srv := NewServer()
+	sockets := make(chan *Socket, 1)
+	release := make(chan struct{})
+	errs := make(chan error, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("join", func(so *Socket, args []string) {
+			sockets <- so
+			<-release
+			errs <- so.Join(args[0])
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:::{"name":"join","args":["lobby"]}`))
+	var so *Socket
+	select {
+	case so = <-sockets:
+	case <-time.After(time.Second):
+		t.Fatal("join not handled")
+	}
+
+	ws.Close()
+	for deadline := time.Now().Add(time.Second); !srv.rooms.hasLeft(so); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("socket did not leave its rooms")
+		}
+	}
+	close(release)
+
+	if err := <-errs; err != ErrorSocketDisconnected {
+		t.Fatalf("join after disconnect returned %v", err)
+	}
+	if n := len(srv.To("lobby").Clients()); n != 0 {
+		t.Fatalf("lobby has %d clients after disconnect", n)
+	}
+	if members, _ := srv.Store.Members(roomKey{room: "lobby"}.storeName()); len(members) != 0 {
+		t.Fatalf("store lists %v in the lobby", members)
+	}
+}
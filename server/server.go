@@ -0,0 +1,451 @@
+/*
+Package server is a socket.io 0.9 server. It serves the handshake and the websocket,
+xhr-polling and jsonp-polling transports, and gives each client a *Socket with the same On, Emit and EmitWithAck API as the client.
+
+	srv := server.NewServer()
+	srv.On("connection", func(so *server.Socket) {
+		so.On("echo", func(so *server.Socket, args []string) []string {
+			return args
+		})
+	})
+	http.ListenAndServe(":4500", srv)
+*/
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruffrey/go-socketio09"
+)
+
+const (
+	// ProtocolVersion is the socket.io protocol revision this server speaks
+	ProtocolVersion = "1"
+
+	// TransportWebsocket is the websocket transport id
+	TransportWebsocket = "websocket"
+
+	// OnConnection handlers are called with each new *Socket
+	OnConnection = "connection"
+
+	queueMaxSize      = 500
+	defaultBufferSize = 1024 * 32
+)
+
+/*
+Server is a socket.io 0.9 server, and an http.Handler. Mount it so that it receives every
+request under Resource. Fields must not be changed once it is serving.
+*/
+type Server struct {
+	// Resource is the path prefix of every socket.io request, "/socket.io" by default
+	Resource string
+	// HeartbeatTimeout is how long a client may take to answer a heartbeat before it is dropped.
+	// Zero tells clients not to send heartbeats at all.
+	HeartbeatTimeout time.Duration
+	// HeartbeatInterval is how often the server sends heartbeats. Zero sends none.
+	HeartbeatInterval time.Duration
+	// CloseTimeout is how long a session may stay without a transport connection, including
+	// after the handshake
+	CloseTimeout time.Duration
+	// AckTimeout is how long EmitWithAck waits for the client's ack
+	AckTimeout time.Duration
+	// PollingDuration is how long a polling request waits for frames before answering with a noop
+	PollingDuration time.Duration
+	// Transports are the transport ids offered in the handshake
+	Transports []string
+	// Origins are the `host:port` origins allowed to handshake and open a websocket, where
+	// either side may be `*`. It is AnyOrigin by default.
+	Origins []string
+	// BufferSize is the websocket read and write buffer size
+	BufferSize int
+	// Codec encodes and decodes all JSON. socketio09.DefaultCodec is used when nil.
+	Codec socketio09.Codec
+	// Store keeps sessions, socket data and room membership, in a MemoryStore by default
+	Store Store
+	// Logger receives the server's log records, with the session id as an attribute where there
+	// is one. Nothing is logged when nil.
+	Logger socketio09.Logger
+	// PubSub relays broadcasts to the other processes sharing Store. It is nil for a single process.
+	PubSub PubSub
+	// Tracer starts spans around EmitWithAck and handler calls. Nothing is traced when nil.
+	Tracer socketio09.Tracer
+	// Propagator, when set, sends the trace context of emitted events in their args, and
+	// continues the trace of received events in their handler spans
+	Propagator socketio09.Propagator
+
+	// node tells this server's PubSub messages apart from those of other processes
+	node          string
+	subscribeOnce sync.Once
+
+	authorize     func(*HandshakeData) (bool, error)
+	authorizeLock sync.RWMutex
+
+	namespaces     map[string]*Namespace
+	namespacesLock sync.RWMutex
+
+	rooms rooms
+
+	sessions     map[string]*session
+	closing      map[string]struct{}
+	sessionsLock sync.RWMutex
+}
+
+/*
+NewServer returns a server with the same default timings as the Node.js 0.9 server.
+*/
+func NewServer() *Server {
+	node, _ := newSessionID()
+	return &Server{
+		Resource:          "/socket.io",
+		HeartbeatTimeout:  60 * time.Second,
+		HeartbeatInterval: 25 * time.Second,
+		CloseTimeout:      60 * time.Second,
+		AckTimeout:        60 * time.Second,
+		PollingDuration:   20 * time.Second,
+		Transports:        []string{TransportWebsocket, TransportXHRPolling, TransportJSONPPolling},
+		Origins:           []string{AnyOrigin},
+		BufferSize:        defaultBufferSize,
+		Store:             NewMemoryStore(),
+		node:              node,
+		namespaces:        make(map[string]*Namespace),
+		sessions:          make(map[string]*session),
+		closing:           make(map[string]struct{}),
+	}
+}
+
+/*
+On adds a handler to the default namespace. The only server event is "connection", fired with
+each *Socket once its transport is connected:
+
+	srv.On("connection", func(so *server.Socket) {})
+*/
+func (s *Server) On(event string, fn interface{}) error {
+	return s.Of("").On(event, fn)
+}
+
+/*
+Authorization sets a function which decides whether a client may handshake. Returning false
+answers 401 Unauthorized, and returning an error wrapping ErrorServiceUnavailable answers
+503 Service Unavailable, to shed load. Any other error answers 500. Values attached with
+HandshakeData.Set are available to every socket of the session.
+
+	srv.Authorization(func(hd *server.HandshakeData) (bool, error) {
+		if overloaded() {
+			return false, server.ErrorServiceUnavailable
+		}
+		cookie, err := hd.Cookie("user")
+		if err != nil {
+			return false, nil
+		}
+		hd.Set("user", cookie.Value)
+		return true, nil
+	})
+*/
+func (s *Server) Authorization(fn func(*HandshakeData) (bool, error)) {
+	s.authorizeLock.Lock()
+	s.authorize = fn
+	s.authorizeLock.Unlock()
+}
+
+/*
+authorized runs the authorization function, if there is one, and returns the HTTP status the
+handshake should be answered with.
+*/
+func (s *Server) authorized(hd *HandshakeData) int {
+	s.authorizeLock.RLock()
+	authorize := s.authorize
+	s.authorizeLock.RUnlock()
+
+	if authorize == nil {
+		return http.StatusOK
+	}
+	ok, err := authorize(hd)
+	switch {
+	case errors.Is(err, ErrorServiceUnavailable):
+		return http.StatusServiceUnavailable
+	case err != nil:
+		return http.StatusInternalServerError
+	case !ok:
+		return http.StatusUnauthorized
+	}
+	return http.StatusOK
+}
+
+/*
+Sockets broadcasts to every socket of the default namespace.
+*/
+func (s *Server) Sockets() *Broadcaster {
+	return s.Of("").Sockets()
+}
+
+/*
+To broadcasts to the sockets of the default namespace which joined room.
+*/
+func (s *Server) To(room string) *Broadcaster {
+	return s.Of("").To(room)
+}
+
+func (s *Server) logger() socketio09.Logger {
+	return socketio09.LoggerWith(s.Logger)
+}
+
+func (s *Server) tracer() socketio09.Tracer {
+	return socketio09.TracerOrNop(s.Tracer)
+}
+
+func (s *Server) codec() socketio09.Codec {
+	if s.Codec == nil {
+		return socketio09.DefaultCodec
+	}
+	return s.Codec
+}
+
+/*
+transport is the connection configuration for each websocket. When the server sends heartbeats
+it drops silent clients itself; otherwise reads time out after the heartbeat timeout, since a
+healthy client sends something at least that often.
+*/
+func (s *Server) transport() *socketio09.WebsocketTransport {
+	receiveTimeout := s.HeartbeatTimeout
+	if s.sendsHeartbeats() {
+		receiveTimeout = 0
+	}
+	return &socketio09.WebsocketTransport{
+		HeartbeatTimeout:       s.HeartbeatTimeout,
+		HeartbeatInterval:      s.HeartbeatInterval,
+		ReceiveTimeout:         receiveTimeout,
+		SendTimeout:            s.HeartbeatTimeout,
+		ConnectionCloseTimeout: s.CloseTimeout,
+		BufferSize:             s.BufferSize,
+		Codec:                  s.Codec,
+	}
+}
+
+/*
+ServeHTTP routes socket.io requests, which look like
+
+	[resource] '/' [protocol version] '/' ( [transport id] '/' [session id] )
+*/
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.subscribe()
+
+	rest := strings.TrimPrefix(r.URL.Path, s.Resource)
+	if rest == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if parts[0] != ProtocolVersion {
+		http.Error(w, "Protocol version not supported.", http.StatusBadRequest)
+		return
+	}
+
+	switch len(parts) {
+	case 1:
+		s.serveHandshake(w, r)
+	case 3:
+		if _, exists := r.URL.Query()["disconnect"]; exists {
+			s.serveForcedDisconnect(w, parts[2])
+			return
+		}
+		s.serveTransport(w, r, parts[1], parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+/*
+serveHandshake provisions a new session and answers with
+
+	[sid] ':' [heartbeat timeout] ':' [close timeout] ':' [transports]
+*/
+func (s *Server) serveHandshake(w http.ResponseWriter, r *http.Request) {
+	if !s.allowCORS(w, r, "handshake refused") {
+		return
+	}
+
+	hd := newHandshakeData(r)
+	if status := s.authorized(hd); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	sid, err := newSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.Store.AddSession(sid, hd); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sess := newSession(s, sid, hd)
+	s.sessionsLock.Lock()
+	s.sessions[sid] = sess
+	s.sessionsLock.Unlock()
+	sess.startCloseTimer()
+
+	heartbeat := ""
+	if s.HeartbeatTimeout > 0 {
+		heartbeat = strconv.Itoa(int(s.HeartbeatTimeout / time.Second))
+	}
+	body := sid + ":" + heartbeat + ":" + strconv.Itoa(int(s.CloseTimeout/time.Second)) + ":" +
+		strings.Join(s.Transports, ",")
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(body))
+}
+
+/*
+serveForcedDisconnect tears down a session for a client whose transport is unusable. The
+"disconnect" handlers have run by the time the request is answered. A session held by another
+process is closed through the PubSub.
+*/
+func (s *Server) serveForcedDisconnect(w http.ResponseWriter, sid string) {
+	if sess, exists := s.session(sid); exists {
+		sess.close(ReasonBooted, true)
+	}
+	if err := s.publish(dispatch{Kick: sid}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveTransport(w http.ResponseWriter, r *http.Request, transport, sid string) {
+	if !s.offersTransport(transport) {
+		http.Error(w, "Transport not supported.", http.StatusBadRequest)
+		return
+	}
+
+	switch transport {
+	case TransportWebsocket:
+		s.serveWebsocket(w, r, sid)
+	case TransportXHRPolling, TransportJSONPPolling:
+		s.servePolling(w, r, transport, sid)
+	default:
+		http.Error(w, "Transport not supported.", http.StatusBadRequest)
+	}
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request, sid string) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  s.BufferSize,
+		WriteBufferSize: s.BufferSize,
+		CheckOrigin:     s.checkOrigin,
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already answered the request
+		return
+	}
+	conn := socketio09.NewWebsocketConnection(ws, s.transport())
+
+	sess, exists := s.handshakenSession(sid)
+	if !exists {
+		conn.WriteMsg(errorFrame("", ReasonNotHandshaken, AdviceReconnect))
+		conn.Close()
+		return
+	}
+	if _, err := sess.attach(TransportWebsocket, conn); err != nil {
+		conn.WriteMsg(attachErrorFrame(err))
+		conn.Close()
+		return
+	}
+	sess.stopCloseTimer()
+
+	sess.open()
+	sess.readLoop()
+}
+
+func (s *Server) offersTransport(transport string) bool {
+	for _, t := range s.Transports {
+		if t == transport {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) session(sid string) (*session, bool) {
+	s.sessionsLock.RLock()
+	defer s.sessionsLock.RUnlock()
+	sess, exists := s.sessions[sid]
+	return sess, exists
+}
+
+/*
+handshakenSession finds the session a transport request is for. A session handshaken by another
+process sharing the Store is taken over by this one, and the other process is told to let go of
+its copy. A session this process is still closing is not taken over, though its Store entry is
+still there while its disconnect handlers run.
+*/
+func (s *Server) handshakenSession(sid string) (*session, bool) {
+	if sess, exists := s.session(sid); exists {
+		return sess, true
+	}
+	hd, exists, err := s.Store.Session(sid)
+	if err != nil || !exists {
+		return nil, false
+	}
+
+	s.sessionsLock.Lock()
+	if sess, exists := s.sessions[sid]; exists {
+		s.sessionsLock.Unlock()
+		return sess, true
+	}
+	if _, closing := s.closing[sid]; closing {
+		s.sessionsLock.Unlock()
+		return nil, false
+	}
+	sess := newSession(s, sid, hd)
+	s.sessions[sid] = sess
+	s.sessionsLock.Unlock()
+
+	sess.startCloseTimer()
+	s.publish(dispatch{Taken: sid})
+	return sess, true
+}
+
+func (s *Server) removeSession(sid string) {
+	s.sessionsLock.Lock()
+	delete(s.sessions, sid)
+	s.sessionsLock.Unlock()
+}
+
+/*
+closingSession removes a session which is being closed, and keeps it from being taken over from
+the Store until closedSession is called once its handlers have run.
+*/
+func (s *Server) closingSession(sid string) {
+	s.sessionsLock.Lock()
+	delete(s.sessions, sid)
+	s.closing[sid] = struct{}{}
+	s.sessionsLock.Unlock()
+}
+
+// closedSession forgets a session closed by closingSession, along with its Store entry
+func (s *Server) closedSession(sid string) {
+	s.Store.RemoveSession(sid)
+	s.sessionsLock.Lock()
+	delete(s.closing, sid)
+	s.sessionsLock.Unlock()
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruffrey/go-socketio09"
+)
+
+type chatMessage struct {
+	Text string `json:"text"`
+}
+
+func connectClient(t *testing.T, ts *httptest.Server) *socketio09.SocketIOClient {
+	c, err := socketio09.NewConnection().Connect(ts.URL + "/socket.io/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+/*
+dialRaw handshakes and opens the websocket transport without a client, to control every frame
+*/
+func dialRaw(t *testing.T, ts *httptest.Server) *websocket.Conn {
+	return dialRawWithHeader(t, ts, nil)
+}
+
+func dialRawWithHeader(t *testing.T, ts *httptest.Server, header http.Header) *websocket.Conn {
+	req, _ := http.NewRequest("GET", ts.URL+"/socket.io/1/", nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	sid := strings.Split(string(body), ":")[0]
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/socket.io/1/websocket/"+sid, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func expectFrame(t *testing.T, ws *websocket.Conn, want string) {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("got frame %q, want %q", data, want)
+	}
+}
+
+func TestHandshakeResponse(t *testing.T) {
+	srv := NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/socket.io/1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	parts := strings.Split(string(body), ":")
+	if len(parts) != 4 || parts[0] == "" {
+		t.Fatalf("unexpected handshake %q", body)
+	}
+	if parts[1] != "60" || parts[2] != "60" || parts[3] != "websocket,xhr-polling,jsonp-polling" {
+		t.Fatalf("unexpected handshake timings %q", body)
+	}
+	if _, exists := srv.session(parts[0]); !exists {
+		t.Fatal("handshake did not provision a session")
+	}
+}
+
+func TestServerEmitAndAck(t *testing.T) {
+	srv := NewServer()
+	connected := make(chan *Socket, 1)
+	disconnected := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("echo", func(so *Socket, args []chatMessage) []chatMessage {
+			return args
+		})
+		so.On("disconnect", func(so *Socket, reason string) {
+			disconnected <- reason
+		})
+		so.Emit("welcome", chatMessage{Text: "hi"})
+		connected <- so
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	welcome := make(chan []chatMessage, 1)
+	c := connectClient(t, ts)
+	c.On("welcome", func(h *socketio09.SocketIOConnection, args []chatMessage) {
+		welcome <- args
+	})
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("connection handler not called")
+	}
+	select {
+	case args := <-welcome:
+		if len(args) != 1 || args[0].Text != "hi" {
+			t.Fatalf("unexpected welcome %v", args)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("welcome not received")
+	}
+
+	result, err := c.EmitWithAck("echo", chatMessage{Text: "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != `[[{"text":"ping"}]]` {
+		t.Fatalf("unexpected ack %q", result)
+	}
+
+	c.Close()
+	select {
+	case reason := <-disconnected:
+		if reason != ReasonSocketEnd {
+			t.Fatalf("unexpected disconnect reason %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("disconnect handler not called")
+	}
+}
+
+func TestServerEmitWithAck(t *testing.T) {
+	srv := NewServer()
+	results := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		go func() {
+			result, err := so.EmitWithAck("question", chatMessage{Text: "?"})
+			if err != nil {
+				t.Error(err)
+			}
+			results <- result
+		}()
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	expectFrame(t, ws, `5:1+::{"name":"question","args":[{"text":"?"}]}`)
+	ws.WriteMessage(websocket.TextMessage, []byte(`6:::1+["yes"]`))
+
+	select {
+	case result := <-results:
+		if result != `["yes"]` {
+			t.Fatalf("unexpected ack %q", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ack not received")
+	}
+}
+
+func TestPlainAck(t *testing.T) {
+	srv := NewServer()
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("echo", func(so *Socket, args []string) []string {
+			return args
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:1::{"name":"echo","args":["a"]}`))
+	expectFrame(t, ws, "6:::1")
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:2::{"name":"unhandled","args":[]}`))
+	expectFrame(t, ws, "6:::2")
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:3+::{"name":"echo","args":["b"]}`))
+	expectFrame(t, ws, `6:::3+[["b"]]`)
+}
+
+func TestUnknownTransportIsRefused(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/socket.io/1/flashsocket/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestForcedDisconnect(t *testing.T) {
+	srv := NewServer()
+	var reason string
+	var reasonLock sync.Mutex
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("disconnect", func(so *Socket, r string) {
+			reasonLock.Lock()
+			reason = r
+			reasonLock.Unlock()
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	c := connectClient(t, ts)
+	clientDisconnected := make(chan struct{})
+	c.On("disconnect", func(c *socketio09.SocketIOConnection) {
+		close(clientDisconnected)
+	})
+	for len(srv.Sockets().Clients()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := c.ForceDisconnect(); err != nil {
+		t.Fatal(err)
+	}
+	// the handlers ran before the request was answered
+	reasonLock.Lock()
+	if reason != ReasonBooted {
+		t.Fatalf("unexpected reason %q", reason)
+	}
+	reasonLock.Unlock()
+	select {
+	case <-clientDisconnected:
+	case <-time.After(time.Second):
+		t.Fatal("client not disconnected")
+	}
+
+	resp, err := http.Get(ts.URL + "/socket.io/1/websocket/unknown?disconnect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestBatchedFrames(t *testing.T) {
+	srv := NewServer()
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("echo", func(so *Socket, args []string) []string {
+			return args
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte(socketio09.EncodePayload([]string{
+		`5:1+::{"name":"echo","args":["a"]}`,
+		`5:2+::{"name":"echo","args":["b"]}`,
+	})))
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		ws.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[string(data)] = true
+	}
+	if !got[`6:::1+[["a"]]`] || !got[`6:::2+[["b"]]`] {
+		t.Fatalf("unexpected acks %v", got)
+	}
+}
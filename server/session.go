@@ -0,0 +1,389 @@
+package server
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+// Disconnect reasons, passed to "disconnect" handlers
+const (
+	// ReasonBooted means the server disconnected the socket
+	ReasonBooted = "booted"
+	// ReasonClientDisconnect means the client sent a disconnect packet
+	ReasonClientDisconnect = "client disconnect"
+	// ReasonSocketEnd means the transport connection was closed or failed
+	ReasonSocketEnd = "socket end"
+	// ReasonInvalidPacket means the client sent a frame which could not be decoded
+	ReasonInvalidPacket = "invalid packet"
+	// ReasonNamespaceDisconnect means the client disconnected from a namespace, but kept its session
+	ReasonNamespaceDisconnect = "client namespace disconnect"
+	// ReasonCloseTimeout means a polling client did not come back within the close timeout
+	ReasonCloseTimeout = "close timeout"
+	// ReasonHeartbeatTimeout means the client did not answer a heartbeat within the heartbeat timeout
+	ReasonHeartbeatTimeout = "heartbeat timeout"
+)
+
+// Error packet reasons and advice, sent in `7` packets
+const (
+	// ReasonNotHandshaken is sent when a transport connects with an unknown session id
+	ReasonNotHandshaken = "client not handshaken"
+	// ReasonUnauthorized is sent when a namespace refuses a client
+	ReasonUnauthorized = "unauthorized"
+	// ReasonInvalidNamespace is sent when a client connects to a namespace which does not exist
+	ReasonInvalidNamespace = "invalid namespace"
+	// ReasonTransportNotSupported is sent when a session is used over a second transport
+	ReasonTransportNotSupported = "transport not supported"
+	// AdviceReconnect tells the client to handshake again
+	AdviceReconnect = "reconnect"
+)
+
+/*
+session is a handshaken client. It owns the outbound queue and routes inbound packets to the
+*Socket of their endpoint. A session is bound to the first transport it is used with: either
+a websocket connection, which it reads and writes itself, or a polling transport, whose
+requests drain the queue.
+*/
+type session struct {
+	id        string
+	server    *Server
+	handshake *HandshakeData
+	logger    socketio09.Logger
+
+	transport   string
+	conn        *socketio09.WebsocketConnection
+	outbound    chan string
+	connectedAt time.Time
+
+	pollLock   sync.Mutex
+	closeTimer *time.Timer
+
+	sockets     map[string]*Socket
+	socketsLock sync.RWMutex
+
+	heartbeats chan struct{}
+
+	closed       bool
+	closeLock    sync.Mutex
+	done         chan struct{}
+	notifyClient bool
+}
+
+func newSession(s *Server, sid string, hd *HandshakeData) *session {
+	return &session{
+		id:         sid,
+		server:     s,
+		handshake:  hd,
+		logger:     socketio09.LoggerWith(s.Logger, "sid", sid),
+		outbound:   make(chan string, queueMaxSize),
+		sockets:    make(map[string]*Socket),
+		heartbeats: make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+/*
+attach binds the session to transport the first time it is used, with conn for websockets.
+first is true for that first time, when the session has to be opened. Polling requests attach
+again on every request, which is fine as long as the transport is the same.
+*/
+func (sess *session) attach(transport string, conn *socketio09.WebsocketConnection) (first bool, err error) {
+	sess.closeLock.Lock()
+	defer sess.closeLock.Unlock()
+
+	if sess.closed {
+		return false, ErrorSocketDisconnected
+	}
+	if sess.transport == "" {
+		sess.transport = transport
+		sess.conn = conn
+		sess.connectedAt = time.Now()
+		return true, nil
+	}
+	if sess.transport != transport || conn != nil {
+		return false, ErrorTransportInUse
+	}
+	return false, nil
+}
+
+/*
+attachErrorFrame is the error packet telling the client why attach failed
+*/
+func attachErrorFrame(err error) string {
+	if err == ErrorTransportInUse {
+		return errorFrame("", ReasonTransportNotSupported, "")
+	}
+	return errorFrame("", ReasonNotHandshaken, AdviceReconnect)
+}
+
+/*
+open starts writing to the websocket connection, if there is one, and connects the default
+endpoint. The "connection" handlers run before any inbound packet is read, so they can
+register handlers without missing events.
+*/
+func (sess *session) open() {
+	so := newSocket(sess, sess.server.Of(""), sess.handshake)
+	sess.socketsLock.Lock()
+	sess.sockets[""] = so
+	sess.socketsLock.Unlock()
+
+	if sess.conn != nil {
+		go sess.writeLoop()
+	}
+	if sess.server.sendsHeartbeats() {
+		go sess.heartbeatLoop()
+	}
+	so.connect()
+}
+
+/*
+connectNamespace handles a `1::` [path] [query] packet. The namespace's authorization runs
+with the query of the packet, and the client gets back either `1::` [path] or an error packet.
+*/
+func (sess *session) connectNamespace(endpoint string) {
+	name, query := splitEndpoint(endpoint)
+	if name == "" {
+		return
+	}
+	if _, exists := sess.socket(name); exists {
+		return
+	}
+
+	ns, exists := sess.server.namespace(name)
+	if !exists {
+		sess.send(errorFrame(name, ReasonInvalidNamespace, ""))
+		return
+	}
+
+	hd := sess.handshake.withQuery(query)
+	ok, err := ns.authorized(hd)
+	if err != nil {
+		sess.send(errorFrame(name, err.Error(), ""))
+		return
+	}
+	if !ok {
+		sess.send(errorFrame(name, ReasonUnauthorized, ""))
+		return
+	}
+
+	so := newSocket(sess, ns, hd)
+	sess.socketsLock.Lock()
+	sess.sockets[name] = so
+	sess.socketsLock.Unlock()
+	so.connect()
+}
+
+/*
+disconnectNamespace removes the socket of one namespace, leaving the session connected.
+*/
+func (sess *session) disconnectNamespace(endpoint, reason string) {
+	sess.socketsLock.Lock()
+	so, exists := sess.sockets[endpoint]
+	delete(sess.sockets, endpoint)
+	sess.socketsLock.Unlock()
+
+	if exists {
+		so.onDisconnect(reason)
+	}
+}
+
+func (sess *session) socket(endpoint string) (*Socket, bool) {
+	sess.socketsLock.RLock()
+	defer sess.socketsLock.RUnlock()
+	so, exists := sess.sockets[endpoint]
+	return so, exists
+}
+
+/*
+send queues a frame for the client.
+*/
+func (sess *session) send(frame string) error {
+	sess.closeLock.Lock()
+	defer sess.closeLock.Unlock()
+
+	if sess.closed {
+		return ErrorSocketDisconnected
+	}
+	select {
+	case sess.outbound <- frame:
+		return nil
+	default:
+		return socketio09.ErrorSocketOverflood
+	}
+}
+
+/*
+readLoop reads frames off the connection until it fails, then closes the session. A message may
+hold several frames joined with the multi-message framing, as batching clients send them.
+*/
+func (sess *session) readLoop() {
+	for {
+		data, err := sess.conn.GetNextMsg()
+		if err != nil {
+			sess.close(ReasonSocketEnd, false)
+			return
+		}
+		frames, err := socketio09.DecodePayload(data)
+		if err != nil {
+			sess.logger.Error("invalid inbound payload", "payload", data, "err", err)
+			sess.close(ReasonInvalidPacket, false)
+			return
+		}
+		for _, frame := range frames {
+			if !sess.receive(frame) {
+				return
+			}
+		}
+	}
+}
+
+/*
+receive decodes and handles one inbound frame. A frame which cannot be decoded closes the
+session, and receive returns false.
+*/
+func (sess *session) receive(frame string) bool {
+	msg, err := socketio09.DecodeInboundMessageWithCodec(frame, sess.server.codec())
+	if err != nil {
+		sess.logger.Error("invalid inbound frame", "frame", frame, "err", err)
+		sess.close(ReasonInvalidPacket, false)
+		return false
+	}
+	sess.handleMessage(msg)
+	return true
+}
+
+func (sess *session) handleMessage(msg *socketio09.Message) {
+	switch msg.Type {
+	case spec.Heartbeat:
+		select {
+		case sess.heartbeats <- struct{}{}:
+		default:
+		}
+	case spec.Disconnect:
+		if msg.Endpoint == "" {
+			sess.close(ReasonClientDisconnect, false)
+			return
+		}
+		sess.disconnectNamespace(msg.Endpoint, ReasonNamespaceDisconnect)
+	case spec.Connect:
+		sess.connectNamespace(msg.Endpoint)
+	case spec.Event:
+		if so, exists := sess.socket(msg.Endpoint); exists {
+			go so.handleEvent(msg)
+		}
+	case spec.Ack:
+		if so, exists := sess.socket(msg.Endpoint); exists {
+			so.handleAck(msg)
+		}
+	}
+}
+
+/*
+writeLoop sends queued frames to the connection. Once the session is closed it flushes what is
+left, tells the client about the disconnect when the server caused it, and closes the connection.
+*/
+func (sess *session) writeLoop() {
+	for {
+		select {
+		case frame := <-sess.outbound:
+			if err := sess.conn.WriteMsg(frame); err != nil {
+				sess.close(ReasonSocketEnd, false)
+				sess.conn.Close()
+				return
+			}
+		case <-sess.done:
+			for len(sess.outbound) > 0 {
+				sess.conn.WriteMsg(<-sess.outbound)
+			}
+			if sess.notifyClient {
+				sess.conn.WriteMsg(spec.Disconnect + "::")
+			}
+			sess.conn.Close()
+			return
+		}
+	}
+}
+
+/*
+close ends the session and fires "disconnect" on all of its sockets with reason. notifyClient
+sends the client a disconnect packet first. The session stays in the Store until the handlers
+have run, so they can still read socket data, but it can no longer be taken over from there.
+*/
+func (sess *session) close(reason string, notifyClient bool) {
+	sess.closeLock.Lock()
+	if sess.closed {
+		sess.closeLock.Unlock()
+		return
+	}
+	sess.closed = true
+	sess.notifyClient = notifyClient
+	close(sess.done)
+	if sess.closeTimer != nil {
+		sess.closeTimer.Stop()
+	}
+	sess.closeLock.Unlock()
+
+	sess.logger.Info("session closed", "reason", reason)
+	sess.server.closingSession(sess.id)
+
+	sess.socketsLock.Lock()
+	sockets := make([]*Socket, 0, len(sess.sockets))
+	for endpoint, so := range sess.sockets {
+		sockets = append(sockets, so)
+		delete(sess.sockets, endpoint)
+	}
+	sess.socketsLock.Unlock()
+
+	for _, so := range sockets {
+		so.onDisconnect(reason)
+	}
+	sess.server.closedSession(sess.id)
+}
+
+/*
+discard drops a session which another process took over. It was never opened, so there are no
+handlers to fire, and the Store entry now belongs to the other process.
+*/
+func (sess *session) discard() {
+	sess.closeLock.Lock()
+	if sess.closed || sess.transport != "" {
+		sess.closeLock.Unlock()
+		return
+	}
+	sess.closed = true
+	close(sess.done)
+	if sess.closeTimer != nil {
+		sess.closeTimer.Stop()
+	}
+	sess.closeLock.Unlock()
+
+	sess.server.removeSession(sess.id)
+}
+
+/*
+splitEndpoint separates the namespace of a connect packet's endpoint from its query
+*/
+func splitEndpoint(endpoint string) (name string, query url.Values) {
+	i := strings.IndexByte(endpoint, '?')
+	if i == -1 {
+		return endpoint, nil
+	}
+	query, _ = url.ParseQuery(endpoint[i+1:])
+	return endpoint[:i], query
+}
+
+/*
+errorFrame makes an error packet, `7::` [endpoint] `:` [reason] `+` [advice]
+*/
+func errorFrame(endpoint, reason, advice string) string {
+	frame, _ := (&socketio09.Packet{
+		Type:     spec.Error,
+		Endpoint: endpoint,
+		Data:     reason + "+" + advice,
+	}).Encode()
+	return frame
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+/*
+Socket is a client connected to the server. Handlers added with On get the *Socket as their
+first argument:
+
+	so.On("chat", func(so *server.Socket, args []ChatMessage) {})
+
+A handler may return one value, which is sent back as the ack when the client asked for one.
+*/
+type Socket struct {
+	session   *session
+	namespace *Namespace
+	endpoint  string
+	handshake *HandshakeData
+
+	handlers handlers
+	acks     socketio09.AckManager
+
+	// left is set once the socket left its rooms on disconnect, under the rooms lock
+	left bool
+}
+
+func newSocket(sess *session, ns *Namespace, hd *HandshakeData) *Socket {
+	return &Socket{
+		session:   sess,
+		namespace: ns,
+		endpoint:  ns.name,
+		handshake: hd,
+	}
+}
+
+// ID is the session id given to the client during the handshake
+func (so *Socket) ID() string {
+	return so.session.id
+}
+
+// Namespace is the namespace the socket is connected to
+func (so *Socket) Namespace() *Namespace {
+	return so.namespace
+}
+
+/*
+Handshake is the handshake data of the session. For a namespace socket, its query includes the
+query of the connect packet.
+*/
+func (so *Socket) Handshake() *HandshakeData {
+	return so.handshake
+}
+
+/*
+Set stores a value for the socket in the server's Store, for handlers to share per-connection
+state such as a nickname or user id. The sockets of one session share their values across
+namespaces, and the values are dropped when the session ends.
+*/
+func (so *Socket) Set(key string, value interface{}) error {
+	return so.session.server.Store.Set(so.ID(), key, value)
+}
+
+/*
+Get returns a value stored with Set, and whether there is one.
+*/
+func (so *Socket) Get(key string) (interface{}, bool, error) {
+	return so.session.server.Store.Get(so.ID(), key)
+}
+
+/*
+Delete removes a value stored with Set.
+*/
+func (so *Socket) Delete(key string) error {
+	return so.session.server.Store.Delete(so.ID(), key)
+}
+
+/*
+On adds a handler to the specified event. "disconnect" handlers may take the reason as a
+string argument.
+*/
+func (so *Socket) On(event string, fn interface{}) error {
+	return so.handlers.on(event, fn)
+}
+
+/*
+Emit creates a packet based on given data and sends it
+*/
+func (so *Socket) Emit(event string, args interface{}) error {
+	return so.EmitContext(context.Background(), event, args)
+}
+
+/*
+EmitContext emits like Emit, sending the trace context of ctx along when the server has a
+Propagator.
+*/
+func (so *Socket) EmitContext(ctx context.Context, event string, args interface{}) error {
+	msg := &socketio09.Message{
+		Type:      spec.Event,
+		EventName: event,
+	}
+	return so.send(ctx, msg, args)
+}
+
+/*
+EmitWithAck sends an event asking for an ack, and waits for the client's response until the
+server's AckTimeout.
+*/
+func (so *Socket) EmitWithAck(event string, args interface{}) (string, error) {
+	return so.EmitWithAckContext(context.Background(), event, args)
+}
+
+/*
+EmitWithAckContext emits like EmitWithAck, within a span started from ctx. The trace context of
+the span is sent along when the server has a Propagator.
+*/
+func (so *Socket) EmitWithAckContext(ctx context.Context, event string, args interface{}) (string, error) {
+	msg := &socketio09.Message{
+		Type:      spec.Event,
+		AckID:     so.acks.NextID(),
+		EventName: event,
+	}
+
+	ctx, span := so.session.server.tracer().Start(ctx, socketio09.SpanEmitWithAck, socketio09.AttrEvent, event,
+		socketio09.AttrAckID, msg.AckID, socketio09.AttrEndpoint, so.endpoint)
+
+	// buffered, so an ack arriving just as we time out does not block the reader
+	listener := make(chan json.RawMessage, 1)
+	so.acks.AddListener(msg.AckID, listener)
+
+	err := so.send(ctx, msg, args)
+	if err != nil {
+		so.acks.RemoveListener(msg.AckID)
+		span.End(err)
+		return "", err
+	}
+	span.SetAttributes(socketio09.AttrPayloadSize, len(msg.Args))
+
+	select {
+	case result := <-listener:
+		span.End(nil)
+		return string(result), nil
+	case <-time.After(so.session.server.AckTimeout):
+		so.acks.RemoveListener(msg.AckID)
+		span.End(socketio09.ErrorSendTimeout)
+		return "", socketio09.ErrorSendTimeout
+	}
+}
+
+/*
+Join adds the socket to room. Rooms are left automatically on disconnect, and joining fails with
+ErrorSocketDisconnected afterwards.
+*/
+func (so *Socket) Join(room string) error {
+	return so.join(roomKey{endpoint: so.endpoint, room: room})
+}
+
+/*
+Leave removes the socket from room.
+*/
+func (so *Socket) Leave(room string) error {
+	key := roomKey{endpoint: so.endpoint, room: room}
+	so.session.server.rooms.leave(key, so)
+	return so.session.server.Store.Leave(key.storeName(), so.ID())
+}
+
+func (so *Socket) join(key roomKey) error {
+	server := so.session.server
+	if !server.rooms.join(key, so) {
+		return ErrorSocketDisconnected
+	}
+	if err := server.Store.Join(key.storeName(), so.ID()); err != nil {
+		return err
+	}
+	if server.rooms.hasLeft(so) {
+		// the socket disconnected while joining, and may have left the Store's rooms first
+		server.Store.Leave(key.storeName(), so.ID())
+		return ErrorSocketDisconnected
+	}
+	return nil
+}
+
+/*
+Rooms lists the rooms the socket has joined.
+*/
+func (so *Socket) Rooms() []string {
+	return so.session.server.rooms.roomsOf(so)
+}
+
+/*
+Broadcast emits to every other socket, leaving this one out:
+
+	so.Broadcast().Emit("joined", so.ID())
+*/
+func (so *Socket) Broadcast() *Broadcaster {
+	return &Broadcaster{
+		server: so.session.server,
+		key:    roomKey{endpoint: so.endpoint},
+		except: so,
+	}
+}
+
+/*
+Disconnect tells the client it is disconnected. For the default namespace the whole session is
+closed, otherwise only this namespace is left.
+*/
+func (so *Socket) Disconnect() {
+	if so.endpoint == "" {
+		so.session.close(ReasonBooted, true)
+		return
+	}
+
+	frame, _ := (&socketio09.Packet{Type: spec.Disconnect, Endpoint: so.endpoint}).Encode()
+	so.session.send(frame)
+	so.session.disconnectNamespace(so.endpoint, ReasonBooted)
+}
+
+func (so *Socket) codec() socketio09.Codec {
+	return so.session.server.codec()
+}
+
+/*
+send encodes the event, with args as its single argument and the trace context of ctx, and
+queues it
+*/
+func (so *Socket) send(ctx context.Context, msg *socketio09.Message, args interface{}) error {
+	msg.Endpoint = so.endpoint
+	frame, err := socketio09.EncodeFrame(ctx, msg, args, so.codec(), so.session.server.Propagator)
+	if err != nil {
+		return err
+	}
+	return so.session.send(frame)
+}
+
+/*
+connect acknowledges the endpoint to the client and fires the "connection" handler
+*/
+func (so *Socket) connect() {
+	frame, _ := (&socketio09.Packet{Type: spec.Connect, Endpoint: so.endpoint}).Encode()
+	so.session.send(frame)
+
+	so.join(roomKey{endpoint: so.endpoint})
+
+	so.namespace.handlers.fire(so.codec(), so, OnConnection, nil)
+}
+
+/*
+handleEvent calls the handler of an event. An event with an id but no `+` is acked as soon as it
+arrives, as the Node.js server does, and only an event asking for a data ack gets the handler's
+result in its ack.
+*/
+func (so *Socket) handleEvent(msg *socketio09.Message) {
+	if msg.AckID != 0 && !msg.AckRequested {
+		so.ack(msg.AckID, nil)
+	}
+	fn, exists := so.handlers.find(msg.EventName)
+	if !exists {
+		return
+	}
+
+	ctx, data := socketio09.ExtractTrace(so.session.server.Propagator, context.Background(), msg.Args)
+	_, span := so.session.server.tracer().Start(ctx, socketio09.SpanHandler, socketio09.AttrEvent, msg.EventName,
+		socketio09.AttrAckID, msg.AckID, socketio09.AttrEndpoint, so.endpoint, socketio09.AttrPayloadSize, len(msg.Args))
+
+	args := fn.NewArgs()
+	if args != nil && len(data) != 0 {
+		if err := so.codec().Unmarshal(data, args); err != nil {
+			so.session.logger.Warn("event args do not fit the handler",
+				"endpoint", so.endpoint, "event", msg.EventName, "err", err)
+			span.End(err)
+			return
+		}
+	}
+
+	out := fn.Call(so, args)
+	span.End(nil)
+	if !msg.AckRequested {
+		return
+	}
+
+	var result interface{}
+	if fn.Out {
+		result = out[0].Interface()
+	}
+	so.ack(msg.AckID, result)
+}
+
+/*
+ack answers an event the client sent with a message id. A nil result sends a simple ack.
+*/
+func (so *Socket) ack(id int, result interface{}) error {
+	msg := &socketio09.Message{
+		Type:     spec.Ack,
+		AckID:    id,
+		Endpoint: so.endpoint,
+	}
+	frame, err := socketio09.EncodeFrame(context.Background(), msg, result, so.codec(), nil)
+	if err != nil {
+		return err
+	}
+	return so.session.send(frame)
+}
+
+func (so *Socket) handleAck(msg *socketio09.Message) {
+	listener, err := so.acks.Listener(msg.AckID)
+	if err != nil {
+		return
+	}
+	listener <- msg.Args
+}
+
+func (so *Socket) onDisconnect(reason string) {
+	so.handlers.fire(so.codec(), so, socketio09.OnDisconnect, reason)
+	for _, key := range so.session.server.rooms.leaveAll(so) {
+		so.session.server.Store.Leave(key.storeName(), so.ID())
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import "sync"
+
+/*
+Store keeps the server state which outlives a single request: handshaken sessions, the data
+sockets keep with Set, and room membership. The default MemoryStore keeps it in the process. A
+store shared by several server processes lets a client handshake with one process and connect
+its transport to another; pair it with a PubSub so broadcasts reach every process.
+
+Values given to Set are stored as they are by MemoryStore. Stores which keep them out of the
+process have to encode them, and may hand back a differently typed value from Get (for example
+a map[string]interface{} for a struct encoded as JSON). Such stores encode the exported fields of
+HandshakeData and its Values, and rebuild it with NewHandshakeData.
+*/
+type Store interface {
+	// AddSession records a handshaken session
+	AddSession(sid string, hd *HandshakeData) error
+	// Session returns the handshake data of a session, if it exists
+	Session(sid string) (hd *HandshakeData, exists bool, err error)
+	// RemoveSession forgets a session, along with its data and room membership
+	RemoveSession(sid string) error
+
+	// Set stores a value for a socket
+	Set(sid, key string, value interface{}) error
+	// Get returns a value stored for a socket
+	Get(sid, key string) (value interface{}, exists bool, err error)
+	// Delete removes a value stored for a socket
+	Delete(sid, key string) error
+
+	// Join adds a socket to a room
+	Join(room, sid string) error
+	// Leave removes a socket from a room
+	Leave(room, sid string) error
+	// Members lists the sockets in a room
+	Members(room string) ([]string, error)
+}
+
+/*
+MemoryStore is a Store for a single process.
+*/
+type MemoryStore struct {
+	sessions map[string]*HandshakeData
+	data     map[string]map[string]interface{}
+	members  map[string]map[string]struct{}
+	joined   map[string]map[string]struct{}
+	lock     sync.RWMutex
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]*HandshakeData),
+		data:     make(map[string]map[string]interface{}),
+		members:  make(map[string]map[string]struct{}),
+		joined:   make(map[string]map[string]struct{}),
+	}
+}
+
+// AddSession records a handshaken session
+func (ms *MemoryStore) AddSession(sid string, hd *HandshakeData) error {
+	ms.lock.Lock()
+	ms.sessions[sid] = hd
+	ms.lock.Unlock()
+	return nil
+}
+
+// Session returns the handshake data of a session, if it exists
+func (ms *MemoryStore) Session(sid string) (*HandshakeData, bool, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	hd, exists := ms.sessions[sid]
+	return hd, exists, nil
+}
+
+// RemoveSession forgets a session, along with its data and room membership
+func (ms *MemoryStore) RemoveSession(sid string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.sessions, sid)
+	delete(ms.data, sid)
+	for room := range ms.joined[sid] {
+		ms.leave(room, sid)
+	}
+	return nil
+}
+
+// Set stores a value for a socket
+func (ms *MemoryStore) Set(sid, key string, value interface{}) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if ms.data[sid] == nil {
+		ms.data[sid] = make(map[string]interface{})
+	}
+	ms.data[sid][key] = value
+	return nil
+}
+
+// Get returns a value stored for a socket
+func (ms *MemoryStore) Get(sid, key string) (interface{}, bool, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	value, exists := ms.data[sid][key]
+	return value, exists, nil
+}
+
+// Delete removes a value stored for a socket
+func (ms *MemoryStore) Delete(sid, key string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	delete(ms.data[sid], key)
+	if len(ms.data[sid]) == 0 {
+		delete(ms.data, sid)
+	}
+	return nil
+}
+
+// Join adds a socket to a room
+func (ms *MemoryStore) Join(room, sid string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if ms.members[room] == nil {
+		ms.members[room] = make(map[string]struct{})
+	}
+	ms.members[room][sid] = struct{}{}
+	if ms.joined[sid] == nil {
+		ms.joined[sid] = make(map[string]struct{})
+	}
+	ms.joined[sid][room] = struct{}{}
+	return nil
+}
+
+// Leave removes a socket from a room
+func (ms *MemoryStore) Leave(room, sid string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.leave(room, sid)
+	return nil
+}
+
+// leave must be called with the lock held
+func (ms *MemoryStore) leave(room, sid string) {
+	delete(ms.members[room], sid)
+	if len(ms.members[room]) == 0 {
+		delete(ms.members, room)
+	}
+	delete(ms.joined[sid], room)
+	if len(ms.joined[sid]) == 0 {
+		delete(ms.joined, sid)
+	}
+}
+
+// Members lists the sockets in a room
+func (ms *MemoryStore) Members(room string) ([]string, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	sids := make([]string, 0, len(ms.members[room]))
+	for sid := range ms.members[room] {
+		sids = append(sids, sid)
+	}
+	return sids, nil
+}
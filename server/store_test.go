@@ -0,0 +1,266 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ms := NewMemoryStore()
+	ms.AddSession("a", &HandshakeData{Address: "127.0.0.1:1"})
+	if hd, exists, _ := ms.Session("a"); !exists || hd.Address != "127.0.0.1:1" {
+		t.Fatal("session not stored")
+	}
+
+	ms.Set("a", "nick", "ann")
+	if value, exists, _ := ms.Get("a", "nick"); !exists || value != "ann" {
+		t.Fatalf("got %v %v", value, exists)
+	}
+	ms.Delete("a", "nick")
+	if _, exists, _ := ms.Get("a", "nick"); exists {
+		t.Fatal("value not deleted")
+	}
+
+	ms.Join(":lobby", "a")
+	ms.Join(":lobby", "b")
+	ms.Set("a", "nick", "ann")
+	ms.RemoveSession("a")
+	if members, _ := ms.Members(":lobby"); len(members) != 1 || members[0] != "b" {
+		t.Fatalf("unexpected members %v", members)
+	}
+	if _, exists, _ := ms.Get("a", "nick"); exists {
+		t.Fatal("data outlived the session")
+	}
+	if _, exists, _ := ms.Session("a"); exists {
+		t.Fatal("session not removed")
+	}
+}
+
+/*
+TestSharedStore runs two servers in one process, as if they were two processes behind a load
+balancer without sticky sessions.
+*/
+func TestSharedStore(t *testing.T) {
+	store, broker := NewMemoryStore(), NewMemoryBroker()
+	joined := make(chan struct{}, 1)
+	var servers []*httptest.Server
+	var srvs []*Server
+	for i := 0; i < 2; i++ {
+		srv := NewServer()
+		srv.Store = store
+		srv.PubSub = broker
+		srv.On(OnConnection, func(so *Socket) {
+			so.On("join", func(so *Socket, args []string) {
+				so.Join(args[0])
+				joined <- struct{}{}
+			})
+		})
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		servers = append(servers, ts)
+		srvs = append(srvs, srv)
+	}
+
+	// handshake with the first process, connect the transport to the second
+	resp, err := http.Get(servers[0].URL + "/socket.io/1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	sid := strings.Split(string(body), ":")[0]
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(servers[1].URL, "http")+"/socket.io/1/websocket/"+sid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:::{"name":"join","args":["lobby"]}`))
+	select {
+	case <-joined:
+	case <-time.After(time.Second):
+		t.Fatal("join not handled")
+	}
+
+	if ids, _ := srvs[0].To("lobby").ClientIDs(); len(ids) != 1 || ids[0] != sid {
+		t.Fatalf("first process sees %v in the room", ids)
+	}
+	if n := len(srvs[0].To("lobby").Clients()); n != 0 {
+		t.Fatalf("first process has %d local sockets", n)
+	}
+
+	srvs[0].To("lobby").Emit("news", "from the first process")
+	expectFrame(t, ws, `5:::{"name":"news","args":["from the first process"]}`)
+	srvs[1].To("lobby").Emit("news", "from the second process")
+	expectFrame(t, ws, `5:::{"name":"news","args":["from the second process"]}`)
+
+	// a forced disconnect reaches the process holding the session
+	resp, err = http.Get(servers[0].URL + "/socket.io/1/websocket/" + sid + "?disconnect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	expectFrame(t, ws, "0::")
+}
+
+func TestSocketData(t *testing.T) {
+	srv := NewServer()
+	disconnected := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("nick", func(so *Socket, args []string) {
+			so.Set("nick", args[0])
+		})
+		so.On("whoami", func(so *Socket) []string {
+			nick, _, _ := so.Get("nick")
+			return []string{nick.(string)}
+		})
+		so.On("disconnect", func(so *Socket, reason string) {
+			if nick, _, _ := so.Get("nick"); nick != "ann" {
+				t.Errorf("nick is %v on disconnect", nick)
+			}
+			disconnected <- so.ID()
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	expectFrame(t, ws, "1::")
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:1+::{"name":"nick","args":["ann"]}`))
+	expectFrame(t, ws, `6:::1`)
+	ws.WriteMessage(websocket.TextMessage, []byte(`5:2+::{"name":"whoami"}`))
+	expectFrame(t, ws, `6:::2+[["ann"]]`)
+	ws.Close()
+
+	select {
+	case sid := <-disconnected:
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			if _, exists, _ := srv.Store.Get(sid, "nick"); !exists {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("data outlived the session")
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("not disconnected")
+	}
+}
+
+/*
+jsonStore keeps sessions encoded as JSON, as a store shared by several processes would
+*/
+type jsonStore struct {
+	*MemoryStore
+	sessions map[string][]byte
+	lock     sync.Mutex
+}
+
+type jsonSession struct {
+	Headers http.Header
+	Query   url.Values
+	Address string
+	Secure  bool
+	Time    time.Time
+	Values  map[string]interface{}
+}
+
+func (js *jsonStore) AddSession(sid string, hd *HandshakeData) error {
+	data, err := json.Marshal(jsonSession{hd.Headers, hd.Query, hd.Address, hd.Secure, hd.Time, hd.Values()})
+	if err != nil {
+		return err
+	}
+	js.lock.Lock()
+	js.sessions[sid] = data
+	js.lock.Unlock()
+	return nil
+}
+
+func (js *jsonStore) Session(sid string) (*HandshakeData, bool, error) {
+	js.lock.Lock()
+	data, exists := js.sessions[sid]
+	js.lock.Unlock()
+	if !exists {
+		return nil, false, nil
+	}
+	var s jsonSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, false, err
+	}
+	return NewHandshakeData(s.Headers, s.Query, s.Address, s.Secure, s.Time, s.Values), true, nil
+}
+
+func (js *jsonStore) RemoveSession(sid string) error {
+	js.lock.Lock()
+	delete(js.sessions, sid)
+	js.lock.Unlock()
+	return js.MemoryStore.RemoveSession(sid)
+}
+
+func TestEncodedSessionTakeover(t *testing.T) {
+	store := &jsonStore{MemoryStore: NewMemoryStore(), sessions: make(map[string][]byte)}
+	users := make(chan interface{}, 1)
+	var servers []*httptest.Server
+	for i := 0; i < 2; i++ {
+		srv := NewServer()
+		srv.Store = store
+		srv.Authorization(func(hd *HandshakeData) (bool, error) {
+			hd.Set("user", hd.Query.Get("user"))
+			return true, nil
+		})
+		srv.On(OnConnection, func(so *Socket) {
+			user, _ := so.Handshake().Get("user")
+			so.Handshake().Set("seen", true)
+			users <- user
+		})
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+		servers = append(servers, ts)
+	}
+
+	resp, err := http.Get(servers[0].URL + "/socket.io/1/?user=ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	sid := strings.Split(string(body), ":")[0]
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(servers[1].URL, "http")+"/socket.io/1/websocket/"+sid, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+
+	select {
+	case user := <-users:
+		if user != "ann" {
+			t.Fatalf("value set during authorization is %v after the takeover", user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection handler not called")
+	}
+}
+
+func TestHandshakeDataLiteral(t *testing.T) {
+	hd := &HandshakeData{Address: "127.0.0.1"}
+	if _, exists := hd.Get("user"); exists {
+		t.Fatal("value found on empty handshake data")
+	}
+	hd.Set("user", "ann")
+	if user, _ := hd.withQuery(nil).Get("user"); user != "ann" {
+		t.Fatalf("copy got %v", user)
+	}
+}
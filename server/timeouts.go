@@ -0,0 +1,68 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+func (s *Server) sendsHeartbeats() bool {
+	return s.HeartbeatInterval > 0 && s.HeartbeatTimeout > 0
+}
+
+/*
+heartbeatLoop sends the client a heartbeat every heartbeat interval, and closes the session
+when no heartbeat comes back within the heartbeat timeout. Heartbeats the client sends on its
+own count as answers too.
+*/
+func (sess *session) heartbeatLoop() {
+	interval := time.NewTimer(sess.server.HeartbeatInterval)
+	defer interval.Stop()
+
+	for {
+		select {
+		case <-interval.C:
+		case <-sess.done:
+			return
+		}
+
+		sess.send(spec.Heartbeat + "::")
+		timeout := time.NewTimer(sess.server.HeartbeatTimeout)
+		select {
+		case <-sess.heartbeats:
+			timeout.Stop()
+		case <-timeout.C:
+			sess.close(ReasonHeartbeatTimeout, false)
+			return
+		case <-sess.done:
+			timeout.Stop()
+			return
+		}
+		interval.Reset(sess.server.HeartbeatInterval)
+	}
+}
+
+/*
+startCloseTimer closes the session once it has been without a transport connection for the
+close timeout: after the handshake, and between polling requests.
+*/
+func (sess *session) startCloseTimer() {
+	sess.closeLock.Lock()
+	defer sess.closeLock.Unlock()
+
+	if sess.closed || sess.server.CloseTimeout <= 0 {
+		return
+	}
+	sess.closeTimer = time.AfterFunc(sess.server.CloseTimeout, func() {
+		sess.close(ReasonCloseTimeout, false)
+	})
+}
+
+func (sess *session) stopCloseTimer() {
+	sess.closeLock.Lock()
+	defer sess.closeLock.Unlock()
+
+	if sess.closeTimer != nil {
+		sess.closeTimer.Stop()
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHeartbeatTimeout(t *testing.T) {
+	srv := NewServer()
+	srv.HeartbeatInterval = 20 * time.Millisecond
+	srv.HeartbeatTimeout = 100 * time.Millisecond
+	reasons := make(chan string, 1)
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("disconnect", func(so *Socket, reason string) {
+			reasons <- reason
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+
+	// answered heartbeats keep the socket connected
+	for i := 0; i < 3; i++ {
+		expectFrame(t, ws, "2::")
+		ws.WriteMessage(websocket.TextMessage, []byte("2::"))
+	}
+	select {
+	case reason := <-reasons:
+		t.Fatalf("disconnected early: %s", reason)
+	default:
+	}
+
+	expectFrame(t, ws, "2::")
+	select {
+	case reason := <-reasons:
+		if reason != ReasonHeartbeatTimeout {
+			t.Fatalf("unexpected reason %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("unanswered heartbeat did not disconnect")
+	}
+}
+
+func TestHandshakenSessionIsReaped(t *testing.T) {
+	srv := NewServer()
+	srv.CloseTimeout = 50 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	sid := handshakeSID(t, ts)
+	if _, exists := srv.session(sid); !exists {
+		t.Fatal("session not registered")
+	}
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		_, exists := srv.session(sid)
+		_, stored, _ := srv.Store.Session(sid)
+		if !exists && !stored {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session never connected was not reaped")
+		}
+	}
+}
+
+func TestWebsocketStopsCloseTimeout(t *testing.T) {
+	srv := NewServer()
+	srv.CloseTimeout = 50 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ws := dialRaw(t, ts)
+	defer ws.Close()
+	expectFrame(t, ws, "1::")
+
+	time.Sleep(100 * time.Millisecond)
+	if n := len(srv.Sockets().Clients()); n != 1 {
+		t.Fatalf("%d sockets connected after the close timeout", n)
+	}
+}
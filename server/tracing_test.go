@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+)
+
+type traceKey struct{}
+
+type testPropagator struct{}
+
+func (testPropagator) Inject(ctx context.Context) map[string]string {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		return map[string]string{"traceparent": id}
+	}
+	return nil
+}
+
+func (testPropagator) Extract(ctx context.Context, fields map[string]string) context.Context {
+	return context.WithValue(ctx, traceKey{}, fields["traceparent"])
+}
+
+type testSpan struct {
+	name, parent string
+	ended        chan string
+}
+
+func (s *testSpan) SetAttributes(...interface{}) {}
+func (s *testSpan) End(error)                    { s.ended <- s.name + " " + s.parent }
+
+type testTracer chan string
+
+func (tr testTracer) Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, socketio09.Span) {
+	parent, _ := ctx.Value(traceKey{}).(string)
+	return context.WithValue(ctx, traceKey{}, name), &testSpan{name, parent, tr}
+}
+
+func TestTracePropagation(t *testing.T) {
+	spans := make(testTracer, 4)
+	srv := NewServer()
+	srv.Tracer = spans
+	srv.Propagator = testPropagator{}
+	srv.On(OnConnection, func(so *Socket) {
+		so.On("echo", func(so *Socket, args []chatMessage) []chatMessage {
+			return args
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	transport := socketio09.NewConnection()
+	transport.Propagator = testPropagator{}
+	c, err := transport.Connect(ts.URL + "/socket.io/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "client")
+	result, err := c.EmitWithAckContext(ctx, "echo", chatMessage{Text: "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != `[[{"text":"ping"}]]` {
+		t.Fatalf("handler got the trace context in its args: %q", result)
+	}
+	select {
+	case span := <-spans:
+		if span != socketio09.SpanHandler+" client" {
+			t.Fatalf("unexpected span %q", span)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler span not ended")
+	}
+}
@@ -0,0 +1,246 @@
+package sociotest
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ruffrey/go-socketio09"
+	"github.com/ruffrey/go-socketio09/spec"
+)
+
+/*
+Conn is one client connected to the fake server. Every frame the client sends is recorded, and
+handed to Next and the Expect methods in order, leaving out heartbeats.
+*/
+type Conn struct {
+	// ID is the session id given in the handshake
+	ID string
+
+	ws        *websocket.Conn
+	writeLock sync.Mutex
+
+	inbound chan string
+	closed  chan struct{}
+
+	received     []string
+	receivedLock sync.Mutex
+
+	replies     map[string]func(args json.RawMessage) interface{}
+	repliesLock sync.RWMutex
+}
+
+func newConn(sid string, ws *websocket.Conn) *Conn {
+	return &Conn{
+		ID:      sid,
+		ws:      ws,
+		inbound: make(chan string, 1024),
+		closed:  make(chan struct{}),
+		replies: make(map[string]func(args json.RawMessage) interface{}),
+	}
+}
+
+func (c *Conn) readLoop() {
+	defer close(c.closed)
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		// batching clients join frames with the multi-message framing
+		frames, err := socketio09.DecodePayload(string(data))
+		if err != nil {
+			frames = []string{string(data)}
+		}
+		for _, frame := range frames {
+			c.receive(frame)
+		}
+	}
+}
+
+func (c *Conn) receive(frame string) {
+	c.receivedLock.Lock()
+	c.received = append(c.received, frame)
+	c.receivedLock.Unlock()
+
+	if frame == spec.Heartbeat+"::" {
+		return
+	}
+	c.reply(frame)
+	c.inbound <- frame
+}
+
+/*
+reply acks an event which asked for an ack, when a reply is registered for it
+*/
+func (c *Conn) reply(frame string) {
+	msg, err := socketio09.DecodeInboundMessage(frame)
+	if err != nil || msg.Type != spec.Event || msg.AckID == 0 {
+		return
+	}
+	c.repliesLock.RLock()
+	fn, exists := c.replies[msg.EventName]
+	c.repliesLock.RUnlock()
+	if exists {
+		c.Ack(msg.AckID, fn(msg.Args))
+	}
+}
+
+/*
+Reply scripts the answer to event: whenever the client emits it with an ack, fn is called with
+the event's args and the ack carries what fn returns. The frames are still recorded and handed
+to Next.
+
+	conn.Reply("test", func(args json.RawMessage) interface{} {
+		return "pong"
+	})
+*/
+func (c *Conn) Reply(event string, fn func(args json.RawMessage) interface{}) {
+	c.repliesLock.Lock()
+	c.replies[event] = fn
+	c.repliesLock.Unlock()
+}
+
+/*
+Send writes a raw frame to the client.
+*/
+func (c *Conn) Send(frame string) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.ws.WriteMessage(websocket.TextMessage, []byte(frame))
+}
+
+/*
+Emit pushes an event to the client. args is the value handed to the client's handler.
+*/
+func (c *Conn) Emit(event string, args interface{}) error {
+	return c.sendMessage(&socketio09.Message{Type: spec.Event, EventName: event}, args)
+}
+
+/*
+Ack answers the client's event with ack id. args is the value the client's EmitWithAck returns,
+encoded.
+*/
+func (c *Conn) Ack(id int, args interface{}) error {
+	return c.sendMessage(&socketio09.Message{Type: spec.Ack, AckID: id}, args)
+}
+
+func (c *Conn) sendMessage(msg *socketio09.Message, args interface{}) error {
+	data, err := json.Marshal([]interface{}{args})
+	if err != nil {
+		return err
+	}
+	msg.Args = data
+	frame, err := socketio09.EncodeOutboundMessage(msg)
+	if err != nil {
+		return err
+	}
+	return c.Send(frame)
+}
+
+/*
+Error sends an error packet, `7::` [endpoint] `:` [reason] `+` [advice].
+*/
+func (c *Conn) Error(endpoint, reason, advice string) error {
+	frame, err := (&socketio09.Packet{Type: spec.Error, Endpoint: endpoint, Data: reason + "+" + advice}).Encode()
+	if err != nil {
+		return err
+	}
+	return c.Send(frame)
+}
+
+/*
+Disconnect sends the client a disconnect packet and closes the connection.
+*/
+func (c *Conn) Disconnect() error {
+	err := c.Send(spec.Disconnect + "::")
+	c.Close()
+	return err
+}
+
+/*
+Close drops the connection without a disconnect packet, as a failing network would.
+*/
+func (c *Conn) Close() error {
+	return c.ws.Close()
+}
+
+/*
+Frames lists every frame received from the client so far, heartbeats included.
+*/
+func (c *Conn) Frames() []string {
+	c.receivedLock.Lock()
+	defer c.receivedLock.Unlock()
+	return append([]string(nil), c.received...)
+}
+
+/*
+Next returns the next frame from the client which is not a heartbeat, waiting up to timeout.
+*/
+func (c *Conn) Next(timeout time.Duration) (string, error) {
+	select {
+	case frame := <-c.inbound:
+		return frame, nil
+	default:
+	}
+	select {
+	case frame := <-c.inbound:
+		return frame, nil
+	case <-c.closed:
+		return "", ErrorClosed
+	case <-time.After(timeout):
+		return "", ErrorTimeout
+	}
+}
+
+/*
+ExpectFrame fails the test unless the next frame from the client is want.
+*/
+func (c *Conn) ExpectFrame(t testing.TB, want string) {
+	t.Helper()
+	frame, err := c.Next(Timeout)
+	if err != nil {
+		t.Fatalf("sociotest: expected frame %q: %v", want, err)
+	}
+	if frame != want {
+		t.Fatalf("sociotest: got frame %q, want %q", frame, want)
+	}
+}
+
+/*
+ExpectEvent fails the test unless the next frame from the client is event. It returns the
+decoded message, whose AckID is set when the client asked for an ack.
+*/
+func (c *Conn) ExpectEvent(t testing.TB, event string) *socketio09.Message {
+	t.Helper()
+	frame, err := c.Next(Timeout)
+	if err != nil {
+		t.Fatalf("sociotest: expected event %q: %v", event, err)
+	}
+	msg, err := socketio09.DecodeInboundMessage(frame)
+	if err != nil {
+		t.Fatalf("sociotest: got invalid frame %q: %v", frame, err)
+	}
+	if msg.Type != spec.Event || msg.EventName != event {
+		t.Fatalf("sociotest: got frame %q, want event %q", frame, event)
+	}
+	return msg
+}
+
+/*
+ExpectDisconnect fails the test unless the client sends a disconnect packet or closes the
+connection next.
+*/
+func (c *Conn) ExpectDisconnect(t testing.TB) {
+	t.Helper()
+	frame, err := c.Next(Timeout)
+	if err == ErrorClosed || frame == spec.Disconnect+"::" {
+		return
+	}
+	if err != nil {
+		t.Fatalf("sociotest: expected a disconnect: %v", err)
+	}
+	t.Fatalf("sociotest: got frame %q, want a disconnect", frame)
+}
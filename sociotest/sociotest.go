@@ -0,0 +1,125 @@
+/*
+Package sociotest is an in-process fake socket.io 0.9 server, for testing code which uses
+socketio09.SocketIOClient without running Node.js. It performs the handshake and speaks the
+websocket transport, and hands each connected client to the test as a *Conn, which sends and
+receives raw frames.
+
+	srv := sociotest.NewServer()
+	defer srv.Close()
+
+	client, err := socketio09.NewConnection().Connect(srv.ConnectURL())
+	conn := srv.Accept(t)
+	conn.Emit("welcome", map[string]string{"message": "hi"})
+
+	msg := conn.ExpectEvent(t, "test")
+	conn.Ack(msg.AckID, "pong")
+*/
+package sociotest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// HeartbeatTimeout is the heartbeat timeout given to clients in the handshake, in seconds
+	HeartbeatTimeout = 60
+	// CloseTimeout is the close timeout given to clients in the handshake, in seconds
+	CloseTimeout = 60
+)
+
+// Timeout is how long Accept and the Expect methods wait
+var Timeout = 2 * time.Second
+
+var (
+	// ErrorTimeout indicates nothing arrived within the time given
+	ErrorTimeout = errors.New("sociotest: timed out")
+	// ErrorClosed indicates the connection is closed
+	ErrorClosed = errors.New("sociotest: connection closed")
+)
+
+/*
+Server is a fake socket.io 0.9 server on a local port. Clients connect to ConnectURL.
+*/
+type Server struct {
+	*httptest.Server
+
+	conns chan *Conn
+}
+
+/*
+NewServer starts a fake server. Close it when the test is done.
+*/
+func NewServer() *Server {
+	s := &Server{conns: make(chan *Conn, 16)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+/*
+ConnectURL is the URL to give to WebsocketTransport.Connect.
+*/
+func (s *Server) ConnectURL() string {
+	return s.URL + "/socket.io/1"
+}
+
+/*
+Accept returns the next client to connect, waiting up to Timeout. The client has been sent the
+`1::` connect packet already.
+*/
+func (s *Server) Accept(t testing.TB) *Conn {
+	t.Helper()
+	select {
+	case c := <-s.conns:
+		return c
+	case <-time.After(Timeout):
+		t.Fatal("sociotest: no client connected")
+		return nil
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "socket.io" && parts[1] == "1":
+		sid, err := newSessionID()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(sid + ":" + strconv.Itoa(HeartbeatTimeout) + ":" + strconv.Itoa(CloseTimeout) + ":websocket"))
+	case len(parts) == 4 && parts[0] == "socket.io" && parts[1] == "1" && parts[2] == "websocket":
+		upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := newConn(parts[3], ws)
+		if err := c.Send("1::"); err != nil {
+			ws.Close()
+			return
+		}
+		go c.readLoop()
+		s.conns <- c
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
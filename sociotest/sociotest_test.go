@@ -0,0 +1,71 @@
+package sociotest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ruffrey/go-socketio09"
+)
+
+type greeting struct {
+	Message string `json:"message"`
+}
+
+func TestFakeServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	client, err := socketio09.NewConnection().Connect(srv.ConnectURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	welcomed := make(chan string, 1)
+	client.On("welcome", func(c *socketio09.SocketIOConnection, args []greeting) {
+		welcomed <- args[0].Message
+	})
+	disconnected := make(chan struct{})
+	client.On("disconnect", func(c *socketio09.SocketIOConnection) {
+		close(disconnected)
+	})
+
+	conn := srv.Accept(t)
+	conn.Emit("welcome", greeting{"hi"})
+	select {
+	case message := <-welcomed:
+		if message != "hi" {
+			t.Fatalf("welcomed with %q", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pushed event not handled")
+	}
+
+	client.Emit("say", "hello")
+	msg := conn.ExpectEvent(t, "say")
+	if string(msg.Args) != `["hello"]` || msg.AckID != 0 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	conn.Reply("ping", func(args json.RawMessage) interface{} {
+		return "pong"
+	})
+	result, err := client.EmitWithAck("ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != `["pong"]` {
+		t.Fatalf("ack carried %s", result)
+	}
+	conn.ExpectFrame(t, `5:1+::{"name":"ping","args":[]}`)
+
+	conn.Error("", "unauthorized", "")
+	conn.Disconnect()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("client not disconnected")
+	}
+	if frames := conn.Frames(); len(frames) < 2 {
+		t.Fatalf("recorded %q", frames)
+	}
+}
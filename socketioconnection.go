@@ -1,7 +1,10 @@
 package socketio09
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -11,11 +14,32 @@ import (
 
 const queueMaxSize = 500
 
+// Disconnect reasons, given to Metrics and the Logger. The error behind a failure is only logged.
+const (
+	// ReasonClientDisconnect means the client was closed with Close
+	ReasonClientDisconnect = "client disconnect"
+	// ReasonServerDisconnect means the server sent a disconnect packet
+	ReasonServerDisconnect = "server disconnect"
+	// ReasonHeartbeatTimeout means nothing was read within the receive timeout
+	ReasonHeartbeatTimeout = "heartbeat timeout"
+	// ReasonReadError means reading from the connection failed
+	ReasonReadError = "read error"
+	// ReasonWriteError means writing to the connection failed
+	ReasonWriteError = "write error"
+	// ReasonInvalidPacket means the server sent a frame which does not parse
+	ReasonInvalidPacket = "invalid packet"
+	// ReasonOverflood means the outbound queue filled up
+	ReasonOverflood = "overflood"
+	// ReasonClosed is given when CloseChannel is called without a reason, or with another error
+	ReasonClosed = "closed"
+)
+
 /*
 SocketIOConnection is a socket.io connection handler object.
 */
 type SocketIOConnection struct {
-	conn WebsocketConnection
+	conn      Conn
+	transport *WebsocketTransport
 
 	outboundMQ chan string
 
@@ -25,6 +49,15 @@ type SocketIOConnection struct {
 	acks AckManager
 
 	requestHeader http.Header
+
+	data     map[string]interface{}
+	dataLock sync.RWMutex
+
+	logger Logger
+
+	rawInbound  []func(frame string)
+	rawOutbound []func(frame string)
+	rawLock     sync.RWMutex
 }
 
 /*
@@ -33,10 +66,30 @@ initChannel create channel, map, and set active
 func (c *SocketIOConnection) initChannel() {
 	//TODO: queueMaxSize from constant to server or client variable
 	c.outboundMQ = make(chan string, queueMaxSize)
-	c.acks.responseListeners = make(map[int](chan string))
+	c.acks.responseListeners = make(map[int](chan json.RawMessage))
 	c.alive = true
 }
 
+/*
+codec returns the JSON codec configured on the transport
+*/
+func (c *SocketIOConnection) codec() Codec {
+	if c.transport == nil || c.transport.Codec == nil {
+		return DefaultCodec
+	}
+	return c.transport.Codec
+}
+
+/*
+log returns the logger of the connection, which discards everything unless one was configured
+*/
+func (c *SocketIOConnection) log() Logger {
+	if c.logger == nil {
+		return discardLogger{}
+	}
+	return c.logger
+}
+
 /*
 IsActive checks that the socket connection is still alive
 */
@@ -45,7 +98,41 @@ func (c *SocketIOConnection) IsActive() bool {
 }
 
 /*
-CloseChannel closes the respoke signaling channel
+Set stores a value on the connection, for handlers to share per-connection state such as a
+nickname or user id. It is safe to call from several handlers at once.
+*/
+func (c *SocketIOConnection) Set(key string, value interface{}) {
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+
+	if c.data == nil {
+		c.data = make(map[string]interface{})
+	}
+	c.data[key] = value
+}
+
+/*
+Get returns a value stored with Set, and whether there is one.
+*/
+func (c *SocketIOConnection) Get(key string) (interface{}, bool) {
+	c.dataLock.RLock()
+	defer c.dataLock.RUnlock()
+	value, exists := c.data[key]
+	return value, exists
+}
+
+/*
+Delete removes a value stored with Set.
+*/
+func (c *SocketIOConnection) Delete(key string) {
+	c.dataLock.Lock()
+	delete(c.data, key)
+	c.dataLock.Unlock()
+}
+
+/*
+CloseChannel closes the respoke signaling channel. args are a reason constant, optionally followed
+by the error behind it.
 */
 func CloseChannel(c *SocketIOConnection, m *eventEmitter, args ...interface{}) error {
 	c.aliveLock.Lock()
@@ -58,6 +145,13 @@ func CloseChannel(c *SocketIOConnection, m *eventEmitter, args ...interface{}) e
 
 	c.conn.Close()
 	c.alive = false
+	reason, err := disconnectReason(args)
+	if err != nil {
+		c.log().Info("connection closed", "reason", reason, "err", err)
+	} else {
+		c.log().Info("connection closed", "reason", reason)
+	}
+	c.transport.metrics().Disconnect(reason)
 
 	// clean handleOutboundMessages
 	for len(c.outboundMQ) > 0 {
@@ -74,29 +168,88 @@ func CloseChannel(c *SocketIOConnection, m *eventEmitter, args ...interface{}) e
 	return nil
 }
 
+/*
+disconnectReason picks the reason for CloseChannel out of its args: a reason constant, an error,
+or a reason followed by the error behind it. Errors are mapped to a reason, so reasons stay a
+small fixed set whatever the transport reports.
+*/
+func disconnectReason(args []interface{}) (reason string, err error) {
+	reason = ReasonClosed
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case string:
+			reason = arg
+		case error:
+			err = arg
+		}
+	}
+	if len(args) == 0 {
+		return reason, err
+	}
+	if _, errorOnly := args[0].(error); errorOnly {
+		switch {
+		case errors.Is(err, ErrorSocketOverflood):
+			reason = ReasonOverflood
+		case errors.Is(err, ErrorProtocolReceivedInvalidPacket):
+			reason = ReasonInvalidPacket
+		}
+	}
+	return reason, err
+}
+
+/*
+readErrorReason tells a read which timed out from other read failures
+*/
+func readErrorReason(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ReasonHeartbeatTimeout
+	}
+	return ReasonReadError
+}
+
 // handleInboundMessages takes incoming message frames from the web socket and transforms
 // them into a meaningful type (json, for example) then bubbles that up to any userland handlers.
+// A multi-message payload from a batching peer is split into its frames first.
 func handleInboundMessages(c *SocketIOConnection, m *eventEmitter) error {
 	for {
-		pkg, err := c.conn.GetNextMsg()
+		data, err := c.conn.GetNextMsg()
 		if err != nil {
-			return CloseChannel(c, m, err)
+			return CloseChannel(c, m, readErrorReason(err), err)
 		}
-		msg, err := DecodeInboundMessage(pkg)
+		frames, err := DecodePayload(data)
 		if err != nil {
-			CloseChannel(c, m, ErrorProtocolReceivedInvalidPacket)
+			c.log().Error("invalid inbound payload", "payload", data, "err", err)
+			CloseChannel(c, m, ReasonInvalidPacket, err)
 			return err
 		}
-
-		switch msg.Type {
-		case spec.Noop:
-			c.outboundMQ <- spec.Heartbeat + "::"
-		default:
-			go m.checkAndFireListenersForValidMessage(c, msg)
+		for _, pkg := range frames {
+			if err := handleInboundFrame(c, m, pkg); err != nil {
+				return err
+			}
 		}
 	}
 }
 
+// handleInboundFrame decodes one frame and handles it, closing the connection if it is invalid
+func handleInboundFrame(c *SocketIOConnection, m *eventEmitter, pkg string) error {
+	c.observe(Inbound, pkg)
+	msg, err := DecodeInboundMessageWithCodec(pkg, c.codec())
+	if err != nil {
+		c.log().Error("invalid inbound frame", "frame", pkg, "err", err)
+		CloseChannel(c, m, ReasonInvalidPacket, err)
+		return err
+	}
+
+	switch msg.Type {
+	case spec.Noop, spec.Heartbeat:
+		c.outboundMQ <- spec.Heartbeat + "::"
+	default:
+		go m.checkAndFireListenersForValidMessage(c, msg)
+	}
+	return nil
+}
+
 var overflooded = make(map[*SocketIOConnection]struct{})
 var overfloodedLock sync.Mutex
 
@@ -116,7 +269,7 @@ func handleOutboundMessages(c *SocketIOConnection, m *eventEmitter) error {
 	for {
 		outBufferLen := len(c.outboundMQ)
 		if outBufferLen >= queueMaxSize-1 {
-			return CloseChannel(c, m, ErrorSocketOverflood)
+			return CloseChannel(c, m, ReasonOverflood, ErrorSocketOverflood)
 		} else if outBufferLen > int(queueMaxSize/2) {
 			overfloodedLock.Lock()
 			overflooded[c] = struct{}{}
@@ -126,34 +279,72 @@ func handleOutboundMessages(c *SocketIOConnection, m *eventEmitter) error {
 			delete(overflooded, c)
 			overfloodedLock.Unlock()
 		}
+		c.transport.metrics().QueueDepth(outBufferLen)
 		// pull the message off the outbound channel and write it to the web socket
 		msg := <-c.outboundMQ
 		if msg[0:1] == spec.Disconnect {
 			return nil
 		}
 
-		err := c.conn.WriteMsg(msg)
-		if err != nil {
-			return CloseChannel(c, m, err)
+		if c.transport.WriteBatchWindow <= 0 {
+			err := c.conn.WriteMsg(msg)
+			if err != nil {
+				return CloseChannel(c, m, ReasonWriteError, err)
+			}
+			c.observe(Outbound, msg)
+			continue
 		}
 
+		batch, closing := gatherOutboundBatch(c, msg)
+		if closing {
+			return nil
+		}
+		err := c.conn.WriteMsgs(batch)
+		if err != nil {
+			return CloseChannel(c, m, ReasonWriteError, err)
+		}
+		for _, msg := range batch {
+			c.observe(Outbound, msg)
+		}
 	}
 }
 
 /*
-send will send an outgoing message packet to the SocketIOConnection.
+gatherOutboundBatch collects more queued frames after first, until the transport's batch window
+elapses or the batch reaches its byte limit. closing is true when a disconnect was pulled from
+the queue, in which case nothing should be written.
 */
-func send(msg *Message, c *SocketIOConnection, args interface{}) error {
-	if args != nil {
-		json, err := json.Marshal(&args)
-		if err != nil {
-			return err
-		}
+func gatherOutboundBatch(c *SocketIOConnection, first string) (batch []string, closing bool) {
+	maxBytes := c.transport.WriteBatchMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = c.transport.BufferSize
+	}
 
-		msg.Args = string(json)
+	batch = []string{first}
+	size := len(first)
+
+	window := c.transport.clock().After(c.transport.WriteBatchWindow)
+
+	for size < maxBytes {
+		select {
+		case msg := <-c.outboundMQ:
+			if msg[0:1] == spec.Disconnect {
+				return nil, true
+			}
+			batch = append(batch, msg)
+			size += len(msg)
+		case <-window:
+			return batch, false
+		}
 	}
+	return batch, false
+}
 
-	command, err := EncodeOutboundMessage(msg)
+/*
+send will send an outgoing message packet to the SocketIOConnection.
+*/
+func send(ctx context.Context, msg *Message, c *SocketIOConnection, args interface{}) error {
+	command, err := EncodeFrame(ctx, msg, args, c.codec(), c.transport.propagator())
 	if err != nil {
 		return err
 	}
@@ -171,38 +362,77 @@ func send(msg *Message, c *SocketIOConnection, args interface{}) error {
 Emit creates a packet based on given data and sends it
 */
 func (c *SocketIOConnection) Emit(method string, args interface{}) error {
+	return c.EmitContext(context.Background(), method, args)
+}
+
+/*
+EmitContext emits like Emit, sending the trace context of ctx along when the transport has a
+Propagator.
+*/
+func (c *SocketIOConnection) EmitContext(ctx context.Context, method string, args interface{}) error {
 	msg := &Message{
 		Type:      spec.Event,
 		EventName: method,
 	}
-	return send(msg, c, args)
+	return send(ctx, msg, c, args)
 }
 
 /*
-EmitWithAck creates an ack frame, then sends it AND waits for a response.
+EmitWithAck creates an ack frame, then sends it AND waits for a response. It fails with
+ErrorSendTimeout after the transport's ReceiveTimeout, or waits indefinitely when that is zero.
 */
 func (c *SocketIOConnection) EmitWithAck(method string, args interface{}) (string, error) {
-	timeout := c.conn.transport.ReceiveTimeout
+	return c.EmitWithAckContext(context.Background(), method, args)
+}
+
+/*
+EmitWithAckContext emits like EmitWithAck, within a span started from ctx. The trace context of
+the span is sent along when the transport has a Propagator. Waiting for the ack stops with the
+error of ctx when it is done.
+*/
+func (c *SocketIOConnection) EmitWithAckContext(ctx context.Context, method string, args interface{}) (string, error) {
+	timeout := c.transport.ReceiveTimeout
 	msg := &Message{
 		Type:      spec.Event,
-		AckID:     c.acks.getNextID(),
+		AckID:     c.acks.NextID(),
 		EventName: method,
 	}
 
-	listener := make(chan string)
-	c.acks.addListener(msg.AckID, listener)
+	ctx, span := c.transport.tracer().Start(ctx, SpanEmitWithAck,
+		AttrEvent, method, AttrAckID, msg.AckID, AttrEndpoint, msg.Endpoint)
 
-	err := send(msg, c, args)
+	// buffered, so an ack arriving just as we time out does not block the reader
+	listener := make(chan json.RawMessage, 1)
+	c.acks.AddListener(msg.AckID, listener)
+
+	sent := c.transport.clock().Now()
+	err := send(ctx, msg, c, args)
 	if err != nil {
-		c.acks.removeListener(msg.AckID)
+		c.acks.RemoveListener(msg.AckID)
+		span.End(err)
+		return "", err
 	}
+	span.SetAttributes(AttrPayloadSize, len(msg.Args))
 
+	// a zero timeout leaves the channel nil, so only ctx ends the wait
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timedOut = c.transport.clock().After(timeout)
+	}
 	select {
 	case result := <-listener:
-		return result, nil
-	case <-time.After(timeout):
-		c.acks.removeListener(msg.AckID)
+		c.transport.metrics().AckRoundTrip(c.transport.clock().Now().Sub(sent))
+		span.End(nil)
+		return string(result), nil
+	case <-timedOut:
+		c.acks.RemoveListener(msg.AckID)
+		c.transport.metrics().AckTimeout()
+		span.End(ErrorSendTimeout)
 		return "", ErrorSendTimeout
+	case <-ctx.Done():
+		c.acks.RemoveListener(msg.AckID)
+		span.End(ctx.Err())
+		return "", ctx.Err()
 	}
 }
 
@@ -211,7 +441,7 @@ heartbeatService sends ping messages for keeping connection alive
 */
 func heartbeatService(c *SocketIOConnection) {
 	for {
-		time.Sleep(c.conn.transport.HeartbeatInterval)
+		c.transport.clock().Sleep(c.transport.HeartbeatInterval)
 		if !c.IsActive() {
 			return
 		}
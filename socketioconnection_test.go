@@ -0,0 +1,166 @@
+package socketio09
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnectionData(t *testing.T) {
+	c := &SocketIOConnection{}
+	if _, exists := c.Get("nick"); exists {
+		t.Fatal("value on a new connection")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Set("key"+strconv.Itoa(i), i)
+			c.Get("nick")
+		}(i)
+	}
+	wg.Wait()
+
+	c.Set("nick", "ann")
+	if value, exists := c.Get("nick"); !exists || value != "ann" {
+		t.Fatalf("got %v %v", value, exists)
+	}
+	c.Delete("nick")
+	if _, exists := c.Get("nick"); exists {
+		t.Fatal("value not deleted")
+	}
+}
+
+// writeCountingConn records the frames of every write call
+type writeCountingConn struct {
+	*PipeConn
+	lock   sync.Mutex
+	writes [][]string
+}
+
+func (wc *writeCountingConn) WriteMsg(message string) error {
+	wc.lock.Lock()
+	wc.writes = append(wc.writes, []string{message})
+	wc.lock.Unlock()
+	return wc.PipeConn.WriteMsg(message)
+}
+
+func (wc *writeCountingConn) WriteMsgs(messages []string) error {
+	wc.lock.Lock()
+	wc.writes = append(wc.writes, messages)
+	wc.lock.Unlock()
+	return wc.PipeConn.WriteMsgs(messages)
+}
+
+func emitAndCountWrites(t *testing.T, window time.Duration, n int) [][]string {
+	clock := NewFakeClock(time.Now())
+	clientEnd, server := NewPipe()
+	conn := &writeCountingConn{PipeConn: clientEnd}
+	transport := NewConnection()
+	transport.WriteBatchWindow = window
+	transport.Clock = clock
+	client := NewClient(conn, transport)
+	defer client.Close()
+
+	for i := 0; i < n; i++ {
+		client.Emit("tick", i)
+	}
+	if window > 0 {
+		// the batch holds every emit once the queue is drained, and waits for its window
+		for len(client.outboundMQ) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.BlockUntil(1)
+		clock.Advance(window)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := server.GetNextMsg(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+	return conn.writes
+}
+
+func TestWriteBatching(t *testing.T) {
+	writes := emitAndCountWrites(t, time.Second, 5)
+	if len(writes) != 1 || len(writes[0]) != 5 {
+		t.Fatalf("5 queued emits took writes %v, want one", writes)
+	}
+}
+
+func TestWriteWithoutBatching(t *testing.T) {
+	writes := emitAndCountWrites(t, 0, 3)
+	if len(writes) != 3 {
+		t.Fatalf("3 emits took writes %v, want one each", writes)
+	}
+	for _, write := range writes {
+		if len(write) != 1 {
+			t.Fatalf("unbatched write of %v", write)
+		}
+	}
+}
+
+func TestEmitWithAckWithoutTimeout(t *testing.T) {
+	clientEnd, server := NewPipe()
+	client := NewClient(clientEnd, NewConnection())
+	defer client.Close()
+
+	results := make(chan error, 1)
+	go func() {
+		_, err := client.EmitWithAck("ping", "x")
+		results <- err
+	}()
+	server.GetNextMsg()
+	select {
+	case err := <-results:
+		t.Fatalf("ack wait without a ReceiveTimeout ended with %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	server.WriteMsg(`6:::1+["pong"]`)
+	if err := <-results; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := client.EmitWithAckContext(ctx, "ping", "y")
+		results <- err
+	}()
+	server.GetNextMsg()
+	cancel()
+	if err := <-results; err != context.Canceled {
+		t.Fatalf("cancelled ack wait ended with %v", err)
+	}
+}
+
+func TestBatchedInboundFrames(t *testing.T) {
+	client, server := NewTestConnection()
+	defer client.Close()
+	received := make(chan string, 2)
+	client.On("say", func(c *SocketIOConnection, args []string) {
+		received <- args[0]
+	})
+
+	server.WriteMsg(EncodePayload([]string{
+		`5:::{"name":"say","args":["one"]}`,
+		`5:::{"name":"say","args":["two"]}`,
+	}))
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case text := <-received:
+			got[text] = true
+		case <-time.After(time.Second):
+			t.Fatalf("batched events not all handled, got %v", got)
+		}
+	}
+	if !got["one"] || !got["two"] {
+		t.Fatalf("handled %v", got)
+	}
+}
@@ -23,3 +23,15 @@ const (
 	// Noop mean dont do anything, I guess
 	Noop = "8"
 )
+
+// ReservedEventNames cannot be used as the name of an Event (5), by clients or servers.
+var ReservedEventNames = []string{
+	"message",
+	"connect",
+	"disconnect",
+	"open",
+	"close",
+	"error",
+	"retry",
+	"reconnect",
+}
@@ -0,0 +1,149 @@
+package socketio09
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// Span names given to Tracer.Start
+const (
+	// SpanHandshake covers the HTTP handshake of Connect
+	SpanHandshake = "socketio.handshake"
+	// SpanEmitWithAck covers an EmitWithAck, from sending the event to receiving its ack
+	SpanEmitWithAck = "socketio.emit_with_ack"
+	// SpanHandler covers decoding the args of an event and calling its handler
+	SpanHandler = "socketio.handler"
+)
+
+// Span attribute keys
+const (
+	AttrEvent       = "socketio.event"
+	AttrAckID       = "socketio.ack_id"
+	AttrEndpoint    = "socketio.endpoint"
+	AttrPayloadSize = "socketio.payload_size"
+)
+
+/*
+Tracer starts spans around handshakes, acked emits and handler calls, so they can be followed
+across services. It is small enough for an OpenTelemetry tracer to implement in a few lines.
+attrs are alternating keys and values, as given to a Logger.
+*/
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, Span)
+}
+
+/*
+Span is one traced operation. End is called once, with the error the operation failed with or
+nil.
+*/
+type Span interface {
+	SetAttributes(attrs ...interface{})
+	End(err error)
+}
+
+/*
+Propagator carries a trace context across the connection, as in an OpenTelemetry
+TextMapPropagator. Inject returns the fields describing the trace of ctx, such as a
+"traceparent", and Extract returns ctx with the trace they describe.
+*/
+type Propagator interface {
+	Inject(ctx context.Context) map[string]string
+	Extract(ctx context.Context, fields map[string]string) context.Context
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...interface{}) {}
+func (nopSpan) End(error)                    {}
+
+/*
+TracerOrNop returns tracer, or a Tracer doing nothing when it is nil.
+*/
+func TracerOrNop(tracer Tracer) Tracer {
+	if tracer == nil {
+		return nopTracer{}
+	}
+	return tracer
+}
+
+func (wst *WebsocketTransport) tracer() Tracer {
+	if wst == nil {
+		return nopTracer{}
+	}
+	return TracerOrNop(wst.Tracer)
+}
+
+func (wst *WebsocketTransport) propagator() Propagator {
+	if wst == nil {
+		return nil
+	}
+	return wst.Propagator
+}
+
+// traceArg is the extra last event arg carrying a trace context
+type traceArg struct {
+	Trace map[string]string `json:"$trace"`
+}
+
+/*
+InjectTrace appends the trace context of ctx to the encoded args of an event, as an extra last
+arg `{"$trace": {...}}`. ExtractTrace removes it again on the other side; peers which do not
+call it see it as one more argument. args are returned as they are when the propagator is nil
+or ctx carries no trace.
+*/
+func InjectTrace(propagator Propagator, ctx context.Context, args json.RawMessage) (json.RawMessage, error) {
+	if propagator == nil {
+		return args, nil
+	}
+	fields := propagator.Inject(ctx)
+	if len(fields) == 0 {
+		return args, nil
+	}
+	arg, err := json.Marshal(traceArg{Trace: fields})
+	if err != nil {
+		return args, err
+	}
+
+	trimmed := bytes.TrimSpace(args)
+	if len(trimmed) < 2 || trimmed[0] != '[' {
+		return json.RawMessage("[" + string(arg) + "]"), nil
+	}
+	body := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+	if len(body) == 0 {
+		return json.RawMessage("[" + string(arg) + "]"), nil
+	}
+	return json.RawMessage("[" + string(body) + "," + string(arg) + "]"), nil
+}
+
+/*
+ExtractTrace removes the trace context added by InjectTrace from the encoded args of an event,
+and returns ctx with the trace it describes. ctx and args are returned as they are when the
+propagator is nil or the args carry no trace.
+*/
+func ExtractTrace(propagator Propagator, ctx context.Context, args json.RawMessage) (context.Context, json.RawMessage) {
+	if propagator == nil || !bytes.Contains(args, []byte(`"$trace"`)) {
+		return ctx, args
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(args, &elements); err != nil || len(elements) == 0 {
+		return ctx, args
+	}
+	var arg traceArg
+	last := elements[len(elements)-1]
+	if err := json.Unmarshal(last, &arg); err != nil || arg.Trace == nil {
+		return ctx, args
+	}
+
+	stripped, err := json.Marshal(elements[:len(elements)-1])
+	if err != nil {
+		return ctx, args
+	}
+	return propagator.Extract(ctx, arg.Trace), stripped
+}
@@ -0,0 +1,137 @@
+package socketio09
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+// testPropagator carries a trace id stored in the context
+type testPropagator struct{}
+
+func (testPropagator) Inject(ctx context.Context) map[string]string {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		return map[string]string{"traceparent": id}
+	}
+	return nil
+}
+
+func (testPropagator) Extract(ctx context.Context, fields map[string]string) context.Context {
+	return context.WithValue(ctx, traceKey{}, fields["traceparent"])
+}
+
+type testSpan struct {
+	name   string
+	parent string
+	attrs  []interface{}
+	err    error
+}
+
+type testTracer struct {
+	ended chan *testSpan
+}
+
+func (tr *testTracer) Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, Span) {
+	parent, _ := ctx.Value(traceKey{}).(string)
+	span := &testSpan{name: name, parent: parent, attrs: attrs}
+	return context.WithValue(ctx, traceKey{}, name), &testSpanHandle{tr, span}
+}
+
+type testSpanHandle struct {
+	tracer *testTracer
+	span   *testSpan
+}
+
+func (h *testSpanHandle) SetAttributes(attrs ...interface{}) {
+	h.span.attrs = append(h.span.attrs, attrs...)
+}
+
+func (h *testSpanHandle) End(err error) {
+	h.span.err = err
+	h.tracer.ended <- h.span
+}
+
+func (s *testSpan) attr(key string) interface{} {
+	for i := 0; i+1 < len(s.attrs); i += 2 {
+		if s.attrs[i] == key {
+			return s.attrs[i+1]
+		}
+	}
+	return nil
+}
+
+func TestTraceArgs(t *testing.T) {
+	ctx := context.WithValue(context.Background(), traceKey{}, "abc")
+	tests := []struct{ args, injected, extracted string }{
+		{``, `[{"$trace":{"traceparent":"abc"}}]`, `[]`},
+		{`[]`, `[{"$trace":{"traceparent":"abc"}}]`, `[]`},
+		{`["hi",{"n":1}]`, `["hi",{"n":1},{"$trace":{"traceparent":"abc"}}]`, `["hi",{"n":1}]`},
+	}
+	for _, test := range tests {
+		injected, err := InjectTrace(testPropagator{}, ctx, json.RawMessage(test.args))
+		if err != nil || string(injected) != test.injected {
+			t.Fatalf("injected %q into %q: %s %v", test.injected, test.args, injected, err)
+		}
+		got, extracted := ExtractTrace(testPropagator{}, context.Background(), injected)
+		if string(extracted) != test.extracted || got.Value(traceKey{}) != "abc" {
+			t.Fatalf("extracted %q from %q, want %q", extracted, injected, test.extracted)
+		}
+	}
+
+	args := json.RawMessage(`["hi"]`)
+	if injected, _ := InjectTrace(nil, ctx, args); string(injected) != `["hi"]` {
+		t.Fatalf("injected without a propagator: %s", injected)
+	}
+	if injected, _ := InjectTrace(testPropagator{}, context.Background(), args); string(injected) != `["hi"]` {
+		t.Fatalf("injected without a trace: %s", injected)
+	}
+	if _, extracted := ExtractTrace(testPropagator{}, ctx, json.RawMessage(`[{"$trace":"x"}]`)); string(extracted) != `[{"$trace":"x"}]` {
+		t.Fatalf("extracted a malformed trace: %s", extracted)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &testTracer{ended: make(chan *testSpan, 4)}
+	clientEnd, server := NewPipe()
+	transport := NewConnection()
+	transport.ReceiveTimeout = time.Second
+	transport.Tracer = tracer
+	transport.Propagator = testPropagator{}
+	client := NewClient(clientEnd, transport)
+	defer client.Close()
+
+	go func() {
+		server.GetNextMsg()
+		server.WriteMsg(`6:::1+["pong"]`)
+	}()
+	ctx := context.WithValue(context.Background(), traceKey{}, "caller")
+	if _, err := client.EmitWithAckContext(ctx, "ping", "x"); err != nil {
+		t.Fatal(err)
+	}
+	span := <-tracer.ended
+	if span.name != SpanEmitWithAck || span.parent != "caller" || span.err != nil ||
+		span.attr(AttrEvent) != "ping" || span.attr(AttrAckID) != 1 || span.attr(AttrPayloadSize) == nil {
+		t.Fatalf("unexpected span %+v", span)
+	}
+
+	client.EmitContext(ctx, "say", "hello")
+	if frame, _ := server.GetNextMsg(); frame != `5:::{"name":"say","args":["hello",{"$trace":{"traceparent":"caller"}}]}` {
+		t.Fatalf("client wrote %q", frame)
+	}
+
+	got := make(chan []string, 1)
+	client.On("welcome", func(c *SocketIOConnection, args []string) {
+		got <- args
+	})
+	server.WriteMsg(`5:::{"name":"welcome","args":["hi",{"$trace":{"traceparent":"remote"}}]}`)
+	if args := <-got; len(args) != 1 || args[0] != "hi" {
+		t.Fatalf("handler got %v", args)
+	}
+	span = <-tracer.ended
+	if span.name != SpanHandler || span.parent != "remote" || span.attr(AttrEvent) != "welcome" {
+		t.Fatalf("unexpected span %+v", span)
+	}
+}
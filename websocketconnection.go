@@ -1,8 +1,12 @@
 package socketio09
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -12,6 +16,9 @@ import (
 
 const wsDefaultBufferSize = 1024 * 32
 
+// defaultForceDisconnectTimeout bounds ForceDisconnect when the transport has no SendTimeout
+const defaultForceDisconnectTimeout = 10 * time.Second
+
 // WebsocketTransport is an object representing defaults for a socket.io transport type
 type WebsocketTransport struct {
 	HeartbeatInterval time.Duration
@@ -23,6 +30,48 @@ type WebsocketTransport struct {
 	ConnectionCloseTimeout time.Duration
 
 	BufferSize int
+
+	// Codec encodes and decodes all JSON on the connection. DefaultCodec is used when nil.
+	Codec Codec
+
+	// WriteBatchWindow enables outbound write batching when it is greater than zero. Frames
+	// queued within the window after the first one are written together as one multi-message
+	// payload, so a burst of emits costs one web socket message instead of one each, at the
+	// price of up to the window of latency on the first.
+	WriteBatchWindow time.Duration
+	// WriteBatchMaxBytes flushes a batch early once it holds this many bytes. Defaults to
+	// BufferSize when zero.
+	WriteBatchMaxBytes int
+
+	// Clock times heartbeats, ack timeouts, write batches and the handshake. SystemClock is used
+	// when nil.
+	Clock Clock
+
+	// Logger receives the client's log records, with the session id and URL as attributes.
+	// Nothing is logged when nil.
+	Logger Logger
+
+	// Metrics observes the client's frames, acks, handshake and disconnect. Nothing is observed
+	// when nil.
+	Metrics Metrics
+
+	// Tracer starts spans around the handshake, EmitWithAck and handler calls. Nothing is traced
+	// when nil.
+	Tracer Tracer
+	// Propagator, when set, sends the trace context of emitted events in their args, and
+	// continues the trace of received events in their handler spans
+	Propagator Propagator
+
+	// Debug, when set, gets every frame read or written, decoded by FormatFrame, one per line
+	// after its direction
+	Debug io.Writer
+}
+
+func (wst *WebsocketTransport) clock() Clock {
+	if wst == nil || wst.Clock == nil {
+		return SystemClock
+	}
+	return wst.Clock
 }
 
 // WebsocketConnection represents the web socket client connection
@@ -31,9 +80,17 @@ type WebsocketConnection struct {
 	transport *WebsocketTransport
 }
 
+/*
+NewWebsocketConnection wraps an established web socket, such as one accepted by a server, using
+the timings of transport.
+*/
+func NewWebsocketConnection(socket *websocket.Conn, transport *WebsocketTransport) *WebsocketConnection {
+	return &WebsocketConnection{socket, transport}
+}
+
 // GetNextMsg reads the latest buffered message into a string
 func (wsc *WebsocketConnection) GetNextMsg() (text string, err error) {
-	wsc.socket.SetReadDeadline(time.Now().Add(wsc.transport.ReceiveTimeout))
+	wsc.socket.SetReadDeadline(deadline(wsc.transport.ReceiveTimeout))
 	msgType, reader, err := wsc.socket.NextReader()
 	if err != nil {
 		return "", err
@@ -59,7 +116,7 @@ func (wsc *WebsocketConnection) GetNextMsg() (text string, err error) {
 
 // WriteMsg writes the exact message to a web socket (should be in protocol format already).
 func (wsc *WebsocketConnection) WriteMsg(message string) error {
-	wsc.socket.SetWriteDeadline(time.Now().Add(wsc.transport.SendTimeout))
+	wsc.socket.SetWriteDeadline(deadline(wsc.transport.SendTimeout))
 	writer, err := wsc.socket.NextWriter(websocket.TextMessage)
 	if err != nil {
 		return err
@@ -74,6 +131,25 @@ func (wsc *WebsocketConnection) WriteMsg(message string) error {
 	return nil
 }
 
+/*
+WriteMsgs writes several frames as a single web socket message, joined with the multi-message
+payload framing of EncodePayload, which socket.io 0.9 servers decode on every transport.
+*/
+func (wsc *WebsocketConnection) WriteMsgs(messages []string) error {
+	return wsc.WriteMsg(EncodePayload(messages))
+}
+
+/*
+deadline turns a timeout into a socket deadline, where a zero timeout means no deadline. The
+socket enforces it against the real time, so it is not read from the transport's Clock.
+*/
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
 // Close just calls close on the underlying websocket
 func (wsc *WebsocketConnection) Close() {
 	wsc.socket.Close()
@@ -90,13 +166,37 @@ SocketIOClient is a Socket.io client container
 type SocketIOClient struct {
 	eventEmitter
 	SocketIOConnection
+
+	// disconnectURL is the transport URL with the `disconnect` query, see ForceDisconnect
+	disconnectURL string
 }
 
 /*
 Close will properly terminate the web socket connection according to socket.io's preferences.
 */
 func (c *SocketIOClient) Close() {
-	CloseChannel(&c.SocketIOConnection, &c.eventEmitter)
+	CloseChannel(&c.SocketIOConnection, &c.eventEmitter, ReasonClientDisconnect)
+}
+
+/*
+ForceDisconnect asks the server over plain HTTP to tear down the session, for when the web socket
+is unusable and a disconnect packet would never arrive, then closes the connection. The request
+gives up after the transport's SendTimeout, or defaultForceDisconnectTimeout without one.
+*/
+func (c *SocketIOClient) ForceDisconnect() error {
+	timeout := c.transport.SendTimeout
+	if timeout <= 0 {
+		timeout = defaultForceDisconnectTimeout
+	}
+	resp, err := (&http.Client{Timeout: timeout}).Get(c.disconnectURL)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("%w: %s", ErrorForceDisconnectFailed, resp.Status)
+		}
+	}
+	c.Close()
+	return err
 }
 
 /*
@@ -117,7 +217,11 @@ func (wst *WebsocketTransport) Connect(fullURL string) (client *SocketIOClient,
 		wsScheme = "ws"
 	}
 
-	hr, err := handshake(fullURL)
+	started := wst.clock().Now()
+	_, span := wst.tracer().Start(context.Background(), SpanHandshake)
+	hr, err := handshake(fullURL, wst.clock())
+	span.End(err)
+	wst.metrics().Handshake(wst.clock().Now().Sub(started), err)
 	if err != nil {
 		return client, err
 	}
@@ -131,6 +235,11 @@ func (wst *WebsocketTransport) Connect(fullURL string) (client *SocketIOClient,
 	wst.ReceiveTimeout = time.Duration(hr.heartbeatTimeout) * time.Second
 
 	urlWithToken.Path = "/socket.io/1/websocket/" + hr.token
+	disconnectURL := *urlWithToken
+	query := disconnectURL.Query()
+	query.Set("disconnect", "1")
+	disconnectURL.RawQuery = query.Encode()
+
 	webSocketURLWithToken := strings.Replace(urlWithToken.String(), urlWithToken.Scheme, wsScheme, 1)
 	dialer := websocket.Dialer{}
 	socket, _, err := dialer.Dial(webSocketURLWithToken, nil)
@@ -138,14 +247,33 @@ func (wst *WebsocketTransport) Connect(fullURL string) (client *SocketIOClient,
 		return client, err
 	}
 
-	client.conn = WebsocketConnection{socket, wst}
+	logger := LoggerWith(wst.Logger, "sid", hr.token, "url", fullURL)
+	client = newClient(&WebsocketConnection{socket, wst}, wst, logger)
+	client.disconnectURL = disconnectURL.String()
+	return client, nil
+}
+
+/*
+NewClient runs a client over an established connection, using the timings of transport. Connect
+uses it with a web socket; tests can use it with a PipeConn or a wrapping Conn.
+*/
+func NewClient(conn Conn, transport *WebsocketTransport) *SocketIOClient {
+	return newClient(conn, transport, LoggerWith(transport.Logger))
+}
+
+func newClient(conn Conn, transport *WebsocketTransport, logger Logger) *SocketIOClient {
+	client := &SocketIOClient{}
+	client.conn = conn
+	client.transport = transport
+	client.logger = logger
 	client.initChannel()
 	client.initMethods()
 	go handleInboundMessages(&client.SocketIOConnection, &client.eventEmitter)
 	go handleOutboundMessages(&client.SocketIOConnection, &client.eventEmitter)
-	go heartbeatService(&client.SocketIOConnection)
-
-	return client, nil
+	if transport.HeartbeatInterval > 0 {
+		go heartbeatService(&client.SocketIOConnection)
+	}
+	return client
 }
 
 /*
@@ -0,0 +1,78 @@
+package socketio09
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestForceDisconnectTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	clientEnd, _ := NewPipe()
+	transport := NewConnection()
+	transport.SendTimeout = 50 * time.Millisecond
+	client := NewClient(clientEnd, transport)
+	client.disconnectURL = ts.URL + "/socket.io/1/websocket/sid?disconnect=1"
+
+	done := make(chan error, 1)
+	go func() { done <- client.ForceDisconnect() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("unanswered forced disconnect succeeded")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("forced disconnect did not time out")
+	}
+	if client.IsActive() {
+		t.Fatal("client still active")
+	}
+}
+
+func TestWebsocketWriteMsgs(t *testing.T) {
+	messages := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			messages <- string(data)
+		}
+	}))
+	defer ts.Close()
+
+	socket, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsc := NewWebsocketConnection(socket, NewConnection())
+	defer wsc.Close()
+
+	frames := []string{`5:::{"name":"a"}`, `5:::{"name":"b"}`, "2::"}
+	if err := wsc.WriteMsgs(frames); err != nil {
+		t.Fatal(err)
+	}
+	wsc.WriteMsg("8::")
+	if got := <-messages; got != EncodePayload(frames) {
+		t.Fatalf("batch sent as %q", got)
+	}
+	if got := <-messages; got != "8::" {
+		t.Fatalf("batch was not one message, next is %q", got)
+	}
+}